@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+// loadAccount obtains the account to render: from the snapshot file when one
+// is given, otherwise from the Rollbar API. A freshly fetched account is also
+// saved as a snapshot when requested.
+//
+// A snapshot only holds the parts of the account it was fetched with, so it
+// is refused when the selected resource types or -teamUsers need more.
+func loadAccount(ctx context.Context, client *fetcher.Client, opts options) (*fetcher.Account, error) {
+	sel := opts.resources.selection(opts.filter, opts.teamUsers)
+	if opts.snapshotIn != "" {
+		account, have, err := readSnapshotFile(opts.snapshotIn)
+		if err != nil {
+			return nil, err
+		}
+		if missing := have.Missing(sel); len(missing) > 0 {
+			return nil, &exitError{code: -1, msg: fmt.Sprintf(
+				"Snapshot %s lacks the %s that this run needs. Fetch it again with the same -resources and -teamUsers, or with every resource type.",
+				opts.snapshotIn, strings.Join(missing, ", "))}
+		}
+		status(opts, color.FgWhite, "Loaded %d projects, %d teams and %d users from %s.",
+			len(account.Projects), len(account.Teams), len(account.Users), opts.snapshotIn)
+		return account, nil
+	}
+
+	// Projects, teams and users are fetched side by side.
+	account, err := client.FetchAccount(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := client.Stats()
+	status(opts, color.FgWhite,
+		"Fetched %d projects, %d access tokens, %d notification rules, %d integrations, %d teams, %d users and %d service links across %d pages.",
+		stats.Projects, stats.AccessTokens, stats.Rules, stats.Integrations, stats.Teams, stats.Users, stats.ServiceLinks, stats.Pages)
+
+	if opts.snapshotOut != "" {
+		if err := writeSnapshotFile(opts.snapshotOut, account, sel); err != nil {
+			return nil, err
+		}
+		status(opts, color.FgWhite, "Saved account snapshot to %s.", opts.snapshotOut)
+	}
+	return account, nil
+}
+
+// readSnapshotFile loads an account snapshot from disk, along with the
+// selection it was fetched with.
+func readSnapshotFile(filename string) (*fetcher.Account, fetcher.Selection, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fetcher.Selection{}, fmt.Errorf("reading snapshot: %w", err)
+	}
+	defer file.Close()
+
+	account, sel, err := fetcher.ReadSnapshot(file)
+	if err != nil {
+		return nil, fetcher.Selection{}, fmt.Errorf("reading snapshot %s: %w", filename, err)
+	}
+	return account, sel, nil
+}
+
+// writeSnapshotFile saves an account snapshot to disk. The snapshot is
+// written to a temporary file first and renamed into place, so that an
+// earlier snapshot is never left half overwritten.
+//
+// Snapshots hold access tokens and integration secrets, so the file keeps
+// the 0600 mode the temporary file is created with.
+func writeSnapshotFile(filename string, account *fetcher.Account, sel fetcher.Selection) error {
+	file, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-")
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := fetcher.WriteSnapshot(file, account, sel); err != nil {
+		file.Close()
+		return fmt.Errorf("writing snapshot %s: %w", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("writing snapshot %s: %w", filename, err)
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		return fmt.Errorf("writing snapshot %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+	"github.com/rollbar/rollbar-terraform-importer/writer"
+)
+
+// version is the importer's version, set at build time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
+// command is a subcommand of the importer. setup registers the command's
+// flags on fs and returns the function that runs it once they are parsed.
+type command struct {
+	name    string
+	summary string
+	setup   func(fs *flag.FlagSet) func() error
+}
+
+var commands = []command{
+	{
+		name:    "fetch",
+		summary: "Fetch the account from the Rollbar API and save it as a JSON snapshot.",
+		setup:   setupFetch,
+	},
+	{
+		name:    "generate",
+		summary: "Render the account as Terraform resources and import commands (the default).",
+		setup:   setupGenerate,
+	},
+	{
+		name:    "imports",
+		summary: "Render only the Terraform import commands or import blocks.",
+		setup:   setupImports,
+	},
+	{
+		name:    "diff",
+		summary: "Compare the account with the Terraform files in a directory.",
+		setup:   setupDiff,
+	},
+	{
+		name:    "version",
+		summary: "Print the importer version.",
+		setup:   setupVersion,
+	},
+}
+
+func setupFetch(fs *flag.FlagSet) func() error {
+	api := addAPIFlags(fs)
+	snapshotOut := fs.String("snapshot-out", "rollbar_account.json", "File to save the account snapshot to.")
+	resources := addResourcesFlag(fs)
+	teamUsers := addTeamUsersFlag(fs)
+
+	return func() error {
+		client, err := api.client()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := api.context()
+		defer cancel()
+
+		_, err = loadAccount(ctx, client, options{snapshotOut: *snapshotOut, resources: resources, teamUsers: *teamUsers})
+		return err
+	}
+}
+
+func setupGenerate(fs *flag.FlagSet) func() error {
+	api := addAPIFlags(fs)
+	output := addOutputFlags(fs)
+	snapshotOut := fs.String("snapshot-out", "", "Save the fetched account to this JSON snapshot file.")
+	snapshotIn := fs.String("snapshot-in", "", "Render from this JSON snapshot file instead of querying the Rollbar API.")
+	names := addNamesFlag(fs)
+	filter := addFilterFlags(fs)
+	resources := addResourcesFlag(fs)
+	teamUsers := addTeamUsersFlag(fs)
+	provider := addProviderFlags(fs)
+
+	return func() error {
+		if err := provider.validate(); err != nil {
+			return err
+		}
+		return runRender(api, output, options{
+			provider:     provider.provider(),
+			singleFile:   *output.singleFile,
+			importBlocks: *output.importBlocks,
+			outPath:      *output.outPath,
+			force:        *output.force,
+			snapshotIn:   *snapshotIn,
+			snapshotOut:  *snapshotOut,
+			names:        writer.Overrides(*names),
+			filter:       filter,
+			resources:    resources,
+			teamUsers:    *teamUsers,
+		})
+	}
+}
+
+func setupImports(fs *flag.FlagSet) func() error {
+	api := addAPIFlags(fs)
+	output := addOutputFlags(fs)
+	snapshotIn := fs.String("snapshot-in", "", "Render from this JSON snapshot file instead of querying the Rollbar API.")
+	names := addNamesFlag(fs)
+	filter := addFilterFlags(fs)
+	resources := addResourcesFlag(fs)
+	teamUsers := addTeamUsersFlag(fs)
+
+	return func() error {
+		return runRender(api, output, options{
+			importBlocks: *output.importBlocks,
+			importsOnly:  true,
+			outPath:      *output.outPath,
+			force:        *output.force,
+			snapshotIn:   *snapshotIn,
+			names:        writer.Overrides(*names),
+			filter:       filter,
+			resources:    resources,
+			teamUsers:    *teamUsers,
+		})
+	}
+}
+
+// runRender validates the flags shared by generate and imports and renders
+// the requested files.
+func runRender(api *apiFlags, output *outputFlags, opts options) error {
+	// An access token is only needed when the API is actually queried.
+	var client *fetcher.Client
+	if opts.snapshotIn == "" {
+		var err error
+		if client, err = api.client(); err != nil {
+			return err
+		}
+	}
+	if err := output.validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := api.context()
+	defer cancel()
+	return generate(ctx, client, opts)
+}
+
+func setupDiff(fs *flag.FlagSet) func() error {
+	api := addAPIFlags(fs)
+	dir := fs.String("dir", ".", "Directory containing the existing Terraform files.")
+	snapshotIn := fs.String("snapshot-in", "", "Compare this JSON snapshot file instead of querying the Rollbar API.")
+	failOnDiff := fs.Bool("exitCode", false, "Exit with status 2 when differences are found.")
+	names := addNamesFlag(fs)
+	filter := addFilterFlags(fs)
+	resources := addResourcesFlag(fs)
+	teamUsers := addTeamUsersFlag(fs)
+
+	return func() error {
+		var client *fetcher.Client
+		if *snapshotIn == "" {
+			var err error
+			if client, err = api.client(); err != nil {
+				return err
+			}
+		}
+
+		ctx, cancel := api.context()
+		defer cancel()
+
+		differences, err := diff(ctx, client, *dir, options{snapshotIn: *snapshotIn, names: writer.Overrides(*names), filter: filter, resources: resources, teamUsers: *teamUsers}, os.Stdout)
+		if err != nil {
+			return err
+		}
+		if differences > 0 && *failOnDiff {
+			return &exitError{code: 2, msg: fmt.Sprintf("Found %d differences.", differences)}
+		}
+		return nil
+	}
+}
+
+func setupVersion(fs *flag.FlagSet) func() error {
+	return func() error {
+		fmt.Println("rollbar-terraform-importer " + version)
+		return nil
+	}
+}
+
+// findCommand returns the subcommand with the given name, or nil.
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// usage prints the list of subcommands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: rollbar-terraform-importer <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run \"rollbar-terraform-importer <command> -h\" for the flags of a command.")
+	fmt.Fprintln(os.Stderr, "Without a command, the flags are those of generate.")
+}
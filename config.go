@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// defaultConfigFile is the config file read from the current directory when
+// -config is not given.
+const defaultConfigFile = ".rollbar-importer.hcl"
+
+// addConfigFlag registers the -config flag on a subcommand's flags.
+func addConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", defaultConfigFile, "HCL file to read default flag values from.")
+}
+
+// loadConfig reads the config file and applies its settings to every flag of
+// fs that was not given on the command line, so that flags always override
+// the file.
+//
+// Every setting is named after a flag, in snake_case (single_file, api_url,
+// snapshot_in). Lists are passed to the flag as comma-separated values and
+// maps as comma-separated key=value pairs, with the keys of nested maps
+// joined by dots. Settings of flags that only exist
+// on other subcommands are ignored, so one file can serve all of them.
+func loadConfig(fs *flag.FlagSet) error {
+	filename := fs.Lookup("config").Value.String()
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) && !given["config"] {
+		return nil
+	}
+
+	file, diags := hclparse.NewParser().ParseHCLFile(filename)
+	if diags.HasErrors() {
+		return &exitError{code: -1, msg: "Invalid config file: " + diags.Error()}
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return &exitError{code: -1, msg: "Invalid config file: " + diags.Error()}
+	}
+
+	known := configKeys()
+	local := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) { local[configKey(f.Name)] = f.Name })
+
+	// Apply the settings in a stable order, so that errors are too.
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		attr := attrs[key]
+		name, ok := local[configKey(key)]
+		if !ok || name == "config" {
+			if !known[configKey(key)] {
+				return configError(attr, fmt.Sprintf("unknown setting %q", key))
+			}
+			continue
+		}
+		if given[name] {
+			continue
+		}
+
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return &exitError{code: -1, msg: "Invalid config file: " + diags.Error()}
+		}
+		flagValue, err := configValue(value)
+		if err != nil {
+			return configError(attr, fmt.Sprintf("%s: %v", key, err))
+		}
+		if err := fs.Set(name, flagValue); err != nil {
+			return configError(attr, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	return nil
+}
+
+// configKeys returns the settings understood by any subcommand.
+func configKeys() map[string]bool {
+	keys := map[string]bool{}
+	for _, cmd := range commands {
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.setup(fs)
+		fs.VisitAll(func(f *flag.Flag) { keys[configKey(f.Name)] = true })
+	}
+	return keys
+}
+
+// configKey normalizes a flag or setting name, so that the snake_case
+// settings match the camelCase and hyphenated flags.
+func configKey(name string) string {
+	name = strings.ReplaceAll(name, "_", "")
+	name = strings.ReplaceAll(name, "-", "")
+	return strings.ToLower(name)
+}
+
+// configValue turns a setting into the string a flag is set with.
+func configValue(value cty.Value) (string, error) {
+	if value.IsNull() {
+		return "", fmt.Errorf("must not be null")
+	}
+
+	ty := value.Type()
+	switch {
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		var values []string
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			s, err := configValue(element)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), nil
+	case ty.IsMapType() || ty.IsObjectType():
+		pairs, err := configPairs("", value)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(pairs, ","), nil
+	}
+
+	s, err := convert.Convert(value, cty.String)
+	if err != nil {
+		return "", err
+	}
+	return s.AsString(), nil
+}
+
+// configPairs turns a map setting into key=value pairs. The keys of nested
+// maps are prefixed with the key of the map that holds them, so that
+// { workspaces = { name = "x" } } becomes workspaces.name=x, as -backendConfig
+// expects. Lists cannot be told apart from the pairs once joined, so they are
+// rejected.
+func configPairs(prefix string, value cty.Value) ([]string, error) {
+	var pairs []string
+	for it := value.ElementIterator(); it.Next(); {
+		key, element := it.Element()
+		name := prefix + key.AsString()
+		ty := element.Type()
+		switch {
+		case element.IsNull():
+			return nil, fmt.Errorf("%s must not be null", name)
+		case ty.IsMapType() || ty.IsObjectType():
+			nested, err := configPairs(name+".", element)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, nested...)
+			continue
+		case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+			return nil, fmt.Errorf("%s must not be a list", name)
+		}
+		s, err := configValue(element)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, name+"="+s)
+	}
+	return pairs, nil
+}
+
+func configError(attr *hcl.Attribute, msg string) error {
+	return &exitError{code: -1, msg: fmt.Sprintf("Invalid config file: %s: %s.", attr.NameRange, msg)}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+// diff compares the account with the Terraform files in dir and prints every
+// resource or data source that differs to w, prefixed with "+" when it is
+// missing from dir, "-" when it is no longer in the account and "~" when its
+// attributes changed.
+//
+// The account is rendered the same way generate() would render it, so a
+// directory produced by generate() from the same account has no differences.
+// Only the account source, naming, filter and resource types of opts are
+// used. It returns the number of differences found.
+func diff(ctx context.Context, client *fetcher.Client, dir string, opts options, w io.Writer) (int, error) {
+	rendered, err := ioutil.TempDir("", "rollbar-terraform-importer-diff")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(rendered)
+
+	opts = options{outPath: rendered, singleFile: true, snapshotIn: opts.snapshotIn, names: opts.names, filter: opts.filter, resources: opts.resources, teamUsers: opts.teamUsers, quiet: true}
+	if err := generate(ctx, client, opts); err != nil {
+		return 0, err
+	}
+
+	want, err := readBlocks(rendered)
+	if err != nil {
+		return 0, err
+	}
+	have, err := readBlocks(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	keys := map[string]bool{}
+	for key := range want {
+		keys[key] = true
+	}
+	for key := range have {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	differences := 0
+	for _, key := range sorted {
+		wantBody, inAccount := want[key]
+		haveBody, inDir := have[key]
+		switch {
+		case inAccount && !inDir:
+			fmt.Fprintf(w, "+ %s\n", key)
+		case !inAccount && inDir:
+			fmt.Fprintf(w, "- %s\n", key)
+		case wantBody != haveBody:
+			fmt.Fprintf(w, "~ %s\n", key)
+		default:
+			continue
+		}
+		differences++
+	}
+
+	if differences == 0 {
+		fmt.Fprintln(w, "No differences found.")
+	}
+	return differences, nil
+}
+
+// readBlocks parses every .tf file in dir and returns the resource and data
+// blocks it contains, keyed by address (e.g. "rollbar_team.backend" or
+// "data.rollbar_team.Owners"), along with their formatted bodies.
+func readBlocks(dir string) (map[string]string, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := map[string]string{}
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", filename, diags.Error())
+		}
+
+		for _, block := range file.Body().Blocks() {
+			var address string
+			switch block.Type() {
+			case "resource":
+				address = strings.Join(block.Labels(), ".")
+			case "data":
+				address = "data." + strings.Join(block.Labels(), ".")
+			default:
+				continue
+			}
+			body := hclwrite.Format(block.Body().BuildTokens(nil).Bytes())
+			blocks[address] = strings.TrimSpace(string(body))
+		}
+	}
+	return blocks, nil
+}
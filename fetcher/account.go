@@ -0,0 +1,148 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Account is everything the importer knows about a Rollbar account: its
+// projects with their access tokens, its teams with their members, its users
+// with their teams and its service links.
+type Account struct {
+	Projects     []Project
+	Teams        []Team
+	Users        []User
+	ServiceLinks []ServiceLink
+}
+
+// Selection picks the parts of an account FetchAccount retrieves. Leaving
+// out the per-project, per-team or per-user parts saves a request for every
+// project, team or user.
+type Selection struct {
+	Projects      bool // the list of projects
+	AccessTokens  bool // the access tokens of every project, implies Projects
+	Notifications bool // the notification rules of every project, implies Projects
+	Integrations  bool // the notification integrations of every project, implies Projects
+	Teams         bool // the list of teams
+	TeamProjects  bool // the projects of every team, implies Teams
+	TeamUsers     bool // the users of every team, implies Teams
+	TeamInvites   bool // the pending invitations of every team, implies Teams
+	Users         bool // the list of users along with the teams of every user
+	ServiceLinks  bool // the list of service links
+}
+
+// SelectAll selects the whole account.
+var SelectAll = Selection{
+	Projects:      true,
+	AccessTokens:  true,
+	Notifications: true,
+	Integrations:  true,
+	Teams:         true,
+	TeamProjects:  true,
+	TeamUsers:     true,
+	TeamInvites:   true,
+	Users:         true,
+	ServiceLinks:  true,
+}
+
+// Missing returns the parts of an account that need selects but s does not,
+// described in a few words each, or nil when s selects everything need does.
+func (s Selection) Missing(need Selection) []string {
+	parts := []struct {
+		have, need bool
+		name       string
+	}{
+		{s.Projects, need.Projects, "projects"},
+		{s.AccessTokens, need.AccessTokens, "project access tokens"},
+		{s.Notifications, need.Notifications, "notification rules"},
+		{s.Integrations, need.Integrations, "integrations"},
+		{s.Teams, need.Teams, "teams"},
+		{s.TeamProjects, need.TeamProjects, "team projects"},
+		{s.TeamUsers, need.TeamUsers, "team users"},
+		{s.TeamInvites, need.TeamInvites, "team invitations"},
+		{s.Users, need.Users, "users"},
+		{s.ServiceLinks, need.ServiceLinks, "service links"},
+	}
+	var missing []string
+	for _, part := range parts {
+		if part.need && !part.have {
+			missing = append(missing, part.name)
+		}
+	}
+	return missing
+}
+
+// FetchAccount retrieves the selected projects, teams, users and service
+// links of the account in parallel. The listings share the Client's rate limiter,
+// so running them side by side never exceeds what a single listing would be
+// allowed.
+//
+// If any listing fails, the others are cancelled and the first failure is
+// returned.
+func (c *Client) FetchAccount(ctx context.Context, sel Selection) (*Account, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var account Account
+	var once sync.Once
+	var firstErr error
+	fail := func(what string, err error) {
+		once.Do(func() {
+			firstErr = fmt.Errorf("fetching %s: %w", what, err)
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	if sel.Projects || sel.AccessTokens || sel.Notifications || sel.Integrations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			projects, err := c.fetchProjects(ctx, sel)
+			if err != nil {
+				fail("projects", err)
+			}
+			account.Projects = projects
+		}()
+	}
+	if sel.Teams || sel.TeamProjects || sel.TeamUsers || sel.TeamInvites {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			teams, err := c.fetchTeams(ctx, sel)
+			if err != nil {
+				fail("teams", err)
+			}
+			account.Teams = teams
+		}()
+	}
+	if sel.Users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users, err := c.FetchUsers(ctx)
+			if err != nil {
+				fail("users", err)
+			}
+			account.Users = users
+		}()
+	}
+	if sel.ServiceLinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serviceLinks, err := c.FetchServiceLinks(ctx)
+			if err != nil {
+				fail("service links", err)
+			}
+			account.ServiceLinks = serviceLinks
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return &account, nil
+}
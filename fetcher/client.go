@@ -0,0 +1,204 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the public Rollbar API endpoint.
+	DefaultBaseURL = "https://api.rollbar.com/api/1/"
+
+	// DefaultUserAgent is sent with every request unless overridden.
+	DefaultUserAgent = "rollbar-terraform-importer"
+
+	// DefaultTimeout bounds each individual HTTP request.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Client talks to the Rollbar API on behalf of a single account access token.
+//
+// A Client is safe to reuse for every request made during an import, which
+// lets the underlying http.Client pool connections.
+type Client struct {
+	accessToken string
+	baseURL     string
+	httpClient  *http.Client
+	userAgent   string
+	headers     http.Header
+	pageSize    int
+	maxRetries  int
+	maxWait     time.Duration
+	concurrency int
+	slots       chan struct{}
+	limiter     rateLimiter
+	stats       statsCounter
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the Client at a different API root, e.g. an on-premise
+// proxy or a local test server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient replaces the http.Client used to perform requests. The
+// client is copied, so later options never modify the caller's value. A nil
+// client restores the default one.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient == nil {
+			c.httpClient = &http.Client{Timeout: DefaultTimeout}
+			return
+		}
+		copied := *httpClient
+		c.httpClient = &copied
+	}
+}
+
+// WithTimeout sets the per-request timeout of the underlying http.Client.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithUserAgent overrides the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithHeader adds an extra header to every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithPageSize sets the number of items requested per page of a listing
+// endpoint. Zero, the default, leaves the page size up to the API.
+func WithPageSize(pageSize int) Option {
+	return func(c *Client) {
+		c.pageSize = pageSize
+	}
+}
+
+// WithMaxRetries sets how many times a request failing with a 429, a 5xx or a
+// transport error is retried.
+func WithMaxRetries(maxRetries int) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithMaxWait caps any single pause between requests, whether it comes from
+// backoff or from the rate limit headers.
+func WithMaxWait(maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.maxWait = maxWait
+	}
+}
+
+// WithConcurrency sets how many per-project, per-team and per-user requests
+// may be in flight at once, across all of them.
+func WithConcurrency(concurrency int) Option {
+	return func(c *Client) {
+		c.concurrency = concurrency
+	}
+}
+
+// NewClient returns a Client authenticating with the given account access
+// token. Options are applied in order, so WithTimeout should come after
+// WithHTTPClient if both are used.
+func NewClient(accessToken string, opts ...Option) *Client {
+	c := &Client{
+		accessToken: accessToken,
+		baseURL:     DefaultBaseURL,
+		httpClient:  &http.Client{Timeout: DefaultTimeout},
+		userAgent:   DefaultUserAgent,
+		headers:     http.Header{},
+		maxRetries:  DefaultMaxRetries,
+		maxWait:     DefaultMaxWait,
+		concurrency: DefaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+	c.slots = make(chan struct{}, c.concurrency)
+	return c
+}
+
+// Stats returns the totals the Client has seen across every request made so
+// far.
+func (c *Client) Stats() Stats {
+	return c.stats.get()
+}
+
+// Stats counts the items retrieved from each kind of listing endpoint, along
+// with the number of pages walked to get them.
+type Stats struct {
+	Projects     int
+	Teams        int
+	Users        int
+	AccessTokens int
+	TeamProjects int
+	TeamUsers    int
+	TeamInvites  int
+	UserTeams    int
+	Rules        int
+	Integrations int
+	ServiceLinks int
+	Pages        int
+	Retries      int
+}
+
+// statsCounter guards a Stats value that is updated while fetching.
+type statsCounter struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func (s *statsCounter) add(update func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.stats)
+}
+
+func (s *statsCounter) get() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// accessTokenKey is the context key under which a request's access token is
+// overridden.
+type accessTokenKey struct{}
+
+// withAccessToken makes the requests made with ctx use the given access token
+// instead of the Client's, e.g. for endpoints that need a project token.
+func withAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, token)
+}
+
+// accessToken returns the access token to send with a request made with ctx.
+func accessToken(ctx context.Context, fallback string) string {
+	if token, ok := ctx.Value(accessTokenKey{}).(string); ok {
+		return token
+	}
+	return fallback
+}
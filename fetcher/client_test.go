@@ -0,0 +1,55 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientOptions(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		fmt.Fprint(w, `{"err": 0, "result": []}`)
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Timeout: time.Minute}
+	client := NewClient("token",
+		WithBaseURL(server.URL+"/proxy/api/1"),
+		WithHTTPClient(httpClient),
+		WithTimeout(time.Second),
+		WithUserAgent("importer-test/1.0"),
+		WithHeader("X-Proxy-Auth", "secret"),
+	)
+	if _, err := client.FetchServiceLinks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.URL.Path != "/proxy/api/1/service_links" {
+		t.Errorf("requested %s, want /proxy/api/1/service_links", got.URL.Path)
+	}
+	headers := map[string]string{
+		"User-Agent":             "importer-test/1.0",
+		"X-Proxy-Auth":           "secret",
+		"X-Rollbar-Access-Token": "token",
+	}
+	for key, want := range headers {
+		if value := got.Header.Get(key); value != want {
+			t.Errorf("got %s header %q, want %q", key, value, want)
+		}
+	}
+	if httpClient.Timeout != time.Minute {
+		t.Errorf("WithTimeout changed the caller's http.Client to %v", httpClient.Timeout)
+	}
+}
+
+func TestWithHTTPClientNil(t *testing.T) {
+	client := NewClient("token", WithHTTPClient(nil), WithTimeout(time.Second))
+	if client.httpClient == nil || client.httpClient.Timeout != time.Second {
+		t.Errorf("got http.Client %+v, want the default one with the given timeout", client.httpClient)
+	}
+}
@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TransportError is returned when a request to the Rollbar API could not be
+// completed at all, e.g. because of a DNS failure or a refused connection.
+type TransportError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("requesting %s: %v", e.Endpoint, e.Err)
+}
+
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// StatusError is returned when the Rollbar API answers with a non-2xx HTTP
+// status code.
+//
+// When the body carries a Rollbar error envelope its message is exposed as
+// Message, otherwise only the raw Body is kept.
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("requesting %s: unexpected HTTP status %d", e.Endpoint, e.StatusCode)
+	if e.Message != "" {
+		return msg + ": " + e.Message
+	}
+	if body := strings.TrimSpace(string(e.Body)); body != "" {
+		msg += ": " + truncate(body, 200)
+	}
+	return msg
+}
+
+// DecodeError is returned when a response body cannot be parsed as the JSON
+// structure expected for the endpoint.
+type DecodeError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding response from %s: %v", e.Endpoint, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// APIError is returned when the Rollbar API responds successfully at the HTTP
+// level but reports a non-zero `err` value in the response envelope.
+type APIError struct {
+	Endpoint string
+	Code     int
+	Message  string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: API returned error %d", e.Endpoint, e.Code)
+	}
+	return fmt.Sprintf("%s: API returned error %d: %s", e.Endpoint, e.Code, e.Message)
+}
+
+// truncate shortens s to at most n bytes so that large error pages do not
+// flood the terminal.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
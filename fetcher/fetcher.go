@@ -1,212 +1,550 @@
 package fetcher
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // FetchProjects retrieves the list of projects in a Rollbar account and
 // returns it as a []Project.
 //
-// Once the list of projects is fetched, the function iterates through the list
-// of projects and appends any access tokens associated with each project.
-func FetchProjects(accessToken string) (projects []Project) {
-	rawProjects := fetch(accessToken, "projects")
+// Once the list of projects is fetched, the function fans out over the list of
+// projects and appends any access tokens, notification rules and
+// integrations associated with each project.
+func (c *Client) FetchProjects(ctx context.Context) ([]Project, error) {
+	return c.fetchProjects(ctx, SelectAll)
+}
 
-	var data projectResponse
-	err := json.Unmarshal([]byte(rawProjects), &data)
+// fetchProjects retrieves the list of projects, along with the access
+// tokens, notification rules and integrations of each project that sel
+// selects.
+func (c *Client) fetchProjects(ctx context.Context, sel Selection) (projects []Project, err error) {
+	err = c.fetchList(ctx, "projects", func(body []byte) (int, error) {
+		var data projectResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		projects = append(projects, data.Result...)
+		return len(data.Result), nil
+	})
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
+		return nil, err
 	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
+	c.stats.add(func(s *Stats) { s.Projects += len(projects) })
+	if !sel.AccessTokens && !sel.Notifications && !sel.Integrations {
+		return projects, nil
 	}
 
-	projects = data.Result
-
-	for i, _ := range projects {
-		fetchProjectAccessTokens(accessToken, &projects[i])
+	err = c.forEach(ctx, len(projects), func(ctx context.Context, i int) error {
+		// The notification rules and integrations are read with the
+		// project's own tokens.
+		if err := c.fetchProjectAccessTokens(ctx, &projects[i]); err != nil {
+			return err
+		}
+		if (sel.Notifications || sel.Integrations) && readToken(&projects[i]) == "" {
+			projects[i].NoReadToken = true
+		}
+		if sel.Notifications {
+			if err := c.fetchProjectNotifications(ctx, &projects[i]); err != nil {
+				return err
+			}
+		}
+		if sel.Integrations {
+			if err := c.fetchProjectIntegrations(ctx, &projects[i]); err != nil {
+				return err
+			}
+		}
+		if !sel.AccessTokens {
+			projects[i].AccessTokens = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return projects
+	return projects, nil
 }
 
 // FetchTeams retrieves the list of teams in a Rollbar account and returns it
 // as a []Team.
 //
-// Once the initial team list is fetched, this function loops through all of
-// the teams and appends any identified projects and users associated with each
-// of the teams.
-func FetchTeams(accessToken string) (teams []Team) {
-	rawTeams := fetch(accessToken, "teams")
+// Once the initial team list is fetched, this function fans out over all of
+// the teams and appends any identified projects, users and pending
+// invitations associated with each of the teams.
+func (c *Client) FetchTeams(ctx context.Context) ([]Team, error) {
+	return c.fetchTeams(ctx, SelectAll)
+}
 
-	var data teamResponse
-	err := json.Unmarshal([]byte(rawTeams), &data)
+// fetchTeams retrieves the list of teams, along with the projects, users and
+// pending invitations of each team that sel selects.
+func (c *Client) fetchTeams(ctx context.Context, sel Selection) (teams []Team, err error) {
+	err = c.fetchList(ctx, "teams", func(body []byte) (int, error) {
+		var data teamResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		teams = append(teams, data.Result...)
+		return len(data.Result), nil
+	})
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
+		return nil, err
 	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
+	c.stats.add(func(s *Stats) { s.Teams += len(teams) })
+	if !sel.TeamProjects && !sel.TeamUsers && !sel.TeamInvites {
+		return teams, nil
 	}
 
-	teams = data.Result
-
-	for i := range teams {
-		fetchTeamProjects(accessToken, &teams[i])
-		fetchTeamUsers(accessToken, &teams[i])
+	err = c.forEach(ctx, len(teams), func(ctx context.Context, i int) error {
+		if sel.TeamProjects {
+			if err := c.fetchTeamProjects(ctx, &teams[i]); err != nil {
+				return err
+			}
+		}
+		if sel.TeamUsers {
+			if err := c.fetchTeamUsers(ctx, &teams[i]); err != nil {
+				return err
+			}
+		}
+		if sel.TeamInvites {
+			return c.fetchTeamInvites(ctx, &teams[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return teams
+	return teams, nil
 }
 
 // FetchUsers retrieves the list of users in a Rollbar account and returns it
 // as a []User.
 //
-// Once the initial user metadata is fetched, this function loops through the
+// Once the initial user metadata is fetched, this function fans out over the
 // list and captures the projects and teams that each user is associated with
 // as []ints.
-func FetchUsers(accessToken string) (users []User) {
-	rawUsers := fetch(accessToken, "users")
-
-	var data userResponse
-	err := json.Unmarshal([]byte(rawUsers), &data)
+func (c *Client) FetchUsers(ctx context.Context) (users []User, err error) {
+	err = c.fetchPages(ctx, "users", func(body []byte) (int, error) {
+		var data userResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		users = append(users, data.Result.Users...)
+		return len(data.Result.Users), nil
+	})
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
+		return nil, err
 	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
+	c.stats.add(func(s *Stats) { s.Users += len(users) })
+
+	err = c.forEach(ctx, len(users), func(ctx context.Context, i int) error {
+		return c.fetchUserTeams(ctx, &users[i])
+	})
+	if err != nil {
+		return nil, err
 	}
+	return users, nil
+}
 
-	users = data.Result.Users
-	for i, _ := range users {
-		fetchUserTeams(accessToken, &users[i])
+// FetchServiceLinks retrieves the list of service links in a Rollbar account
+// and returns it as a []ServiceLink.
+func (c *Client) FetchServiceLinks(ctx context.Context) (serviceLinks []ServiceLink, err error) {
+	err = c.fetchList(ctx, "service_links", func(body []byte) (int, error) {
+		var data serviceLinkResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		serviceLinks = append(serviceLinks, data.Result...)
+		return len(data.Result), nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return users
+	c.stats.add(func(s *Stats) { s.ServiceLinks += len(serviceLinks) })
+	return serviceLinks, nil
 }
 
 // fetchProjectAccessTokens retrieves the access tokens a given project is
 // associated with.
 //
-// It lacks a return value, as it appends the returned access tokens to the
-// passed Project struct's AccesTokens property.
-func fetchProjectAccessTokens(accessToken string, project *Project) {
+// It appends the returned access tokens to the passed Project struct's
+// AccessTokens property and only returns an error.
+func (c *Client) fetchProjectAccessTokens(ctx context.Context, project *Project) error {
 	projectID := strconv.Itoa(project.ID)
 	endpoint := "project/" + projectID + "/access_tokens"
-	rawProjectAccessTokens := fetch(accessToken, endpoint)
 
-	var data accessTokenResponse
-	err := json.Unmarshal([]byte(rawProjectAccessTokens), &data)
+	err := c.fetchList(ctx, endpoint, func(body []byte) (int, error) {
+		var data accessTokenResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		project.AccessTokens = append(project.AccessTokens, data.Result...)
+		return len(data.Result), nil
+	})
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
+		return err
 	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
+	c.stats.add(func(s *Stats) { s.AccessTokens += len(project.AccessTokens) })
+	return nil
+}
+
+// NotificationChannels are the channels whose notification rules and
+// integration settings are fetched for every project.
+var NotificationChannels = []string{"email", "slack", "pagerduty", "webhook"}
+
+// fetchProjectNotifications retrieves the notification rules of a given
+// project on every channel.
+//
+// The notification endpoints only accept project access tokens, so the rules
+// are read with one of the project's tokens that has the read scope. Projects
+// without such a token are skipped, and marked with NoReadToken by
+// fetchProjects. It appends the returned rules to the
+// passed Project struct's Notifications property and only returns an error.
+func (c *Client) fetchProjectNotifications(ctx context.Context, project *Project) error {
+	token := readToken(project)
+	if token == "" {
+		return nil
 	}
+	ctx = withAccessToken(ctx, token)
 
-	accessTokens := data.Result
-	for _, accessToken := range accessTokens {
-		project.AccessTokens = append(project.AccessTokens, accessToken)
+	for _, channel := range NotificationChannels {
+		endpoint := "notifications/" + channel + "/rules"
+		err := c.fetchList(ctx, endpoint, func(body []byte) (int, error) {
+			var data notificationRulesResponse
+			if err := json.Unmarshal(body, &data); err != nil {
+				return 0, err
+			}
+			for _, rule := range data.Result {
+				rule.Channel = channel
+				project.Notifications = append(project.Notifications, rule)
+			}
+			return len(data.Result), nil
+		})
+		if err != nil {
+			return err
+		}
 	}
+	c.stats.add(func(s *Stats) { s.Rules += len(project.Notifications) })
+	return nil
+}
+
+// fetchProjectIntegrations retrieves the settings of a given project's
+// integration with every notification channel.
+//
+// Like the notification rules, the integrations are read with one of the
+// project's tokens that has the read scope, and projects without one are
+// skipped. Channels the project has never been integrated with answer with a
+// 404 and are left out. It appends the integrations to the passed Project
+// struct's Integrations property and only returns an error.
+func (c *Client) fetchProjectIntegrations(ctx context.Context, project *Project) error {
+	token := readToken(project)
+	if token == "" {
+		return nil
+	}
+	ctx = withAccessToken(ctx, token)
+
+	for _, channel := range NotificationChannels {
+		endpoint := "notifications/" + channel
+		body, err := c.fetchResult(ctx, endpoint)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var data integrationResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return &DecodeError{Endpoint: endpoint, Err: err}
+		}
+		if len(data.Result) == 0 {
+			continue
+		}
+		project.Integrations = append(project.Integrations, Integration{Channel: channel, Settings: data.Result})
+	}
+	c.stats.add(func(s *Stats) { s.Integrations += len(project.Integrations) })
+	return nil
+}
+
+// readToken returns an enabled access token of project with the read scope,
+// or an empty string if it has none.
+func readToken(project *Project) string {
+	for _, accessToken := range project.AccessTokens {
+		if accessToken.Status == "disabled" {
+			continue
+		}
+		for _, scope := range accessToken.Scopes {
+			if scope == "read" {
+				return accessToken.AccessToken
+			}
+		}
+	}
+	return ""
 }
 
 // fetchTeamProjects retrieves the projects a given team is associated with.
 //
-// It lacks a return value as it appends the returned teams to the
-// passed Team struct's Projects property.
-func fetchTeamProjects(accessToken string, team *Team) {
+// It appends the returned projects to the passed Team struct's Projects
+// property and only returns an error.
+func (c *Client) fetchTeamProjects(ctx context.Context, team *Team) error {
 	teamID := strconv.Itoa(team.ID)
 	endpoint := "team/" + teamID + "/projects"
-	rawTeamProjects := fetch(accessToken, endpoint)
 
-	var data teamProjectsResponse
-	err := json.Unmarshal([]byte(rawTeamProjects), &data)
+	err := c.fetchList(ctx, endpoint, func(body []byte) (int, error) {
+		var data teamProjectsResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		for _, project := range data.Result {
+			team.Projects = append(team.Projects, project.ProjectID)
+		}
+		return len(data.Result), nil
+	})
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
-	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
-	}
-
-	for _, project := range data.Result {
-		team.Projects = append(team.Projects, project.ProjectID)
+		return err
 	}
+	c.stats.add(func(s *Stats) { s.TeamProjects += len(team.Projects) })
+	return nil
 }
 
 // fetchTeamUsers retrieves the users a given team is associated with.
 //
-// It lacks a return value as it appends the returned teams to the
-// passed Team struct's Users property.
-func fetchTeamUsers(accessToken string, team *Team) {
+// It appends the returned users to the passed Team struct's Users property
+// and only returns an error.
+func (c *Client) fetchTeamUsers(ctx context.Context, team *Team) error {
 	teamID := strconv.Itoa(team.ID)
 	endpoint := "team/" + teamID + "/users"
-	rawTeamUsers := fetch(accessToken, endpoint)
 
-	var data teamUsersResponse
-	err := json.Unmarshal([]byte(rawTeamUsers), &data)
+	err := c.fetchPages(ctx, endpoint, func(body []byte) (int, error) {
+		var data teamUsersResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		for _, user := range data.Result {
+			team.Users = append(team.Users, user.UserID)
+		}
+		return len(data.Result), nil
+	})
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
-	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
+		return err
 	}
+	c.stats.add(func(s *Stats) { s.TeamUsers += len(team.Users) })
+	return nil
+}
+
+// fetchTeamInvites retrieves the pending invitations of a given team.
+//
+// Invitations that were accepted, rejected or cancelled are left out, as
+// accepted ones already show up among the team's users. It appends the
+// pending invitations to the passed Team struct's Invites property and only
+// returns an error.
+func (c *Client) fetchTeamInvites(ctx context.Context, team *Team) error {
+	teamID := strconv.Itoa(team.ID)
+	endpoint := "team/" + teamID + "/invites"
 
-	for _, user := range data.Result {
-		team.Users = append(team.Users, user.UserID)
+	err := c.fetchPages(ctx, endpoint, func(body []byte) (int, error) {
+		var data invitationResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		for _, invitation := range data.Result {
+			if invitation.Status == "pending" {
+				team.Invites = append(team.Invites, invitation)
+			}
+		}
+		return len(data.Result), nil
+	})
+	if err != nil {
+		return err
 	}
+	c.stats.add(func(s *Stats) { s.TeamInvites += len(team.Invites) })
+	return nil
 }
 
 // fetchUserTeams retrieves the teams a given user is associated with.
 //
-// It lacks a return value as it appends the returned teams to the
-// passed User struct's Teams property.
-func fetchUserTeams(accessToken string, user *User) {
+// It appends the returned teams to the passed User struct's Teams property
+// and only returns an error.
+func (c *Client) fetchUserTeams(ctx context.Context, user *User) error {
 	userID := strconv.Itoa(user.ID)
 	endpoint := "user/" + userID + "/teams"
-	rawUserTeams := fetch(accessToken, endpoint)
 
-	var data userTeamResponse
-	err := json.Unmarshal([]byte(rawUserTeams), &data)
+	err := c.fetchList(ctx, endpoint, func(body []byte) (int, error) {
+		var data userTeamResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return 0, err
+		}
+		user.Teams = append(user.Teams, data.Result.Teams...)
+		return len(data.Result.Teams), nil
+	})
+	if err != nil {
+		return err
+	}
+	c.stats.add(func(s *Stats) { s.UserTeams += len(user.Teams) })
+	return nil
+}
+
+// fetchList requests a listing endpoint that returns every item in a single
+// response, handing the body to decode, which returns the number of items it
+// found.
+func (c *Client) fetchList(ctx context.Context, endpoint string, decode func(body []byte) (int, error)) error {
+	body, err := c.fetchResult(ctx, endpoint)
 	if err != nil {
-		log.Fatal("Error parsing JSON response body.", err)
+		return err
 	}
-	if data.Err != 0 {
-		log.Fatal("API returned an error", data.Result)
+	if _, err := decode(body); err != nil {
+		return &DecodeError{Endpoint: endpoint, Err: err}
 	}
+	c.stats.add(func(s *Stats) { s.Pages++ })
+	return nil
+}
 
-	teams := data.Result.Teams
+// maxPages is the most pages fetchPages walks of a single listing. A listing
+// with more is assumed to ignore the page parameter rather than to be that
+// long.
+const maxPages = 1000
 
-	for _, team := range teams {
-		user.Teams = append(user.Teams, team)
+// fetchPages walks every page of a paginated listing endpoint, handing each
+// response body to decodePage, which returns the number of items found on
+// the page. Only the users, team users and team invites listings are
+// paginated by the API; the others are requested once with fetchList.
+//
+// Walking stops at the first empty page or when the API returns the same page
+// twice (which is what happens on endpoints that ignore the page parameter).
+// A short page does not end the listing, as the API may return fewer items
+// per page than per_page asks for. A listing that goes on for more than
+// maxPages pages is an error.
+func (c *Client) fetchPages(ctx context.Context, endpoint string, decodePage func(body []byte) (int, error)) error {
+	var previous []byte
+	for page := 1; ; page++ {
+		if page > maxPages {
+			return fmt.Errorf("requesting %s: giving up after %d pages", endpoint, maxPages)
+		}
+		pageEndpoint := c.pageEndpoint(endpoint, page)
+		body, err := c.fetchResult(ctx, pageEndpoint)
+		if err != nil {
+			return err
+		}
+		if previous != nil && bytes.Equal(body, previous) {
+			return nil
+		}
+		previous = body
+
+		n, err := decodePage(body)
+		if err != nil {
+			return &DecodeError{Endpoint: pageEndpoint, Err: err}
+		}
+		c.stats.add(func(s *Stats) { s.Pages++ })
+
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// pageEndpoint appends the pagination query parameters to an endpoint.
+func (c *Client) pageEndpoint(endpoint string, page int) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
 	}
+	endpoint += separator + "page=" + strconv.Itoa(page)
+	if c.pageSize > 0 {
+		endpoint += "&per_page=" + strconv.Itoa(c.pageSize)
+	}
+	return endpoint
+}
+
+// fetchResult requests the given endpoint and returns the response body once
+// its envelope has been checked, so that a non-zero `err` value is surfaced as
+// an *APIError rather than as a confusing decode failure.
+func (c *Client) fetchResult(ctx context.Context, endpoint string) ([]byte, error) {
+	body, err := c.fetch(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, &DecodeError{Endpoint: endpoint, Err: err}
+	}
+	if env.Err != 0 {
+		return nil, &APIError{Endpoint: endpoint, Code: env.Err, Message: env.Message}
+	}
+	return body, nil
 }
 
 // fetch will make an HTTP GET request to the requested API endpoint and return
-// the response body. If it cannot, the importer will throw a fatal error and
-// die, as none of this works unless all the requests are completed
-// successfully.
-func fetch(accessToken string, endpoint string) (body []byte) {
-	baseURL := "https://api.rollbar.com/api/1/"
-	apiURL := baseURL + endpoint
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", apiURL, nil)
+// the response body. Transport failures are returned as a *TransportError and
+// non-2xx responses as a *StatusError.
+//
+// Requests that hit the rate limit, a server error or a transport failure are
+// retried with backoff up to the Client's retry limit. Once ctx is done, the
+// in-flight request is aborted and ctx.Err() is returned.
+func (c *Client) fetch(ctx context.Context, endpoint string) (body []byte, err error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		var header http.Header
+		body, header, err = c.do(ctx, endpoint)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil || attempt >= c.maxRetries || !retryable(err) {
+			return body, err
+		}
+
+		c.stats.add(func(s *Stats) { s.Retries++ })
+		if err := sleep(ctx, retryDelay(attempt, header, c.maxWait)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do performs a single HTTP GET request against the API and returns the body
+// along with the response headers, which are also fed to the rate limiter.
+func (c *Client) do(ctx context.Context, endpoint string) (body []byte, header http.Header, err error) {
+	apiURL := c.baseURL + endpoint
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
-		log.Fatal("Unable to generate HTTP request.", err)
+		return nil, nil, err
 	}
 
-	req.Header.Set("X-Rollbar-Access-Token", accessToken)
+	for key, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("X-Rollbar-Access-Token", accessToken(ctx, c.accessToken))
 
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		log.Fatal("Error reading HTTP response.", err)
+		return nil, nil, &TransportError{Endpoint: endpoint, Err: err}
 	}
 	defer resp.Body.Close()
+	c.limiter.update(resp.Header, c.maxWait)
 
 	body, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal("Error reading response body.", err)
+		return nil, resp.Header, &TransportError{Endpoint: endpoint, Err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var env envelope
+		_ = json.Unmarshal(body, &env)
+		return nil, resp.Header, &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode, Message: env.Message, Body: body}
 	}
-	return body
+	return body, resp.Header, nil
 }
@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFetchPagesStopsAtEmptyPage(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("page") == "1" {
+			fmt.Fprint(w, `{"err": 0, "result": [{"id": 1}, {"id": 2}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"err": 0, "result": []}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithPageSize(3))
+	team := Team{ID: 1}
+	if err := client.fetchTeamUsers(context.Background(), &team); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || len(team.Users) != 2 {
+		t.Errorf("got %d users in %d requests, want 2 users in 2 requests", len(team.Users), requests)
+	}
+}
+
+func TestFetchUsersPageSmallerThanRequested(t *testing.T) {
+	// The server returns at most two users per page, whatever per_page asks
+	// for.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var users []string
+		for id := (page-1)*2 + 1; id <= page*2 && id <= 3; id++ {
+			users = append(users, fmt.Sprintf(`{"id": %d}`, id))
+		}
+		fmt.Fprintf(w, `{"err": 0, "result": {"users": [%s]}}`, strings.Join(users, ", "))
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithPageSize(100))
+	users, err := client.FetchUsers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Errorf("got %d users, want 3", len(users))
+	}
+}
+
+func TestFetchListRequestsOnce(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		fmt.Fprint(w, `{"err": 0, "result": [{"id": 1, "name": "web"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithPageSize(100))
+	projects, err := client.fetchProjects(context.Background(), Selection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || len(requests) != 1 || requests[0] != "/projects" {
+		t.Errorf("got %d projects from %q, want 1 project from a single unpaginated request", len(projects), requests)
+	}
+}
+
+func TestFetchPagesGivesUp(t *testing.T) {
+	// The server ignores the page parameter but never returns the same body
+	// twice.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"err": 0, "result": [{"id": 1}], "page": %q}`, r.URL.Query().Get("page"))
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithPageSize(1))
+	err := client.fetchPages(context.Background(), "projects", func(body []byte) (int, error) {
+		return 1, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "giving up") {
+		t.Fatalf("got %v, want an error after %d pages", err, maxPages)
+	}
+	if pages := client.Stats().Pages; pages != maxPages {
+		t.Errorf("walked %d pages, want %d", pages, maxPages)
+	}
+}
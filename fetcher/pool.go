@@ -0,0 +1,72 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is the number of sub-resource requests a Client runs in
+// parallel when fanning out over projects, teams or users.
+const DefaultConcurrency = 4
+
+// forEach calls fn for every index in [0, n) using at most the Client's
+// configured number of workers.
+//
+// The limit is shared by every forEach of the Client, so fanning out over
+// projects, teams and users at the same time never runs more calls at once
+// than a single fan-out would.
+//
+// Callers write their results into index-addressed slots, so the output order
+// never depends on scheduling. Once any call fails, the context handed to the
+// remaining calls is cancelled, no further calls are started, and that first
+// error is returned.
+func (c *Client) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := c.concurrency
+	if workers > n {
+		workers = n
+	}
+
+	var once sync.Once
+	var firstErr error
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				select {
+				case c.slots <- struct{}{}:
+				case <-ctx.Done():
+					continue
+				}
+				err := fn(ctx, i)
+				<-c.slots
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,59 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyIsSharedAcrossFanOuts(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) == 1 {
+			switch parts[0] {
+			case "users":
+				fmt.Fprint(w, `{"err": 0, "result": {"users": [{"id": 1}, {"id": 2}, {"id": 3}]}}`)
+			case "service_links":
+				fmt.Fprint(w, `{"err": 0, "result": []}`)
+			default:
+				fmt.Fprint(w, `{"err": 0, "result": [{"id": 1}, {"id": 2}, {"id": 3}]}`)
+			}
+			return
+		}
+
+		// A per-project, per-team or per-user request.
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		if parts[0] == "user" {
+			fmt.Fprint(w, `{"err": 0, "result": {"teams": []}}`)
+		} else {
+			fmt.Fprint(w, `{"err": 0, "result": []}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithConcurrency(1))
+	if _, err := client.FetchAccount(context.Background(), SelectAll); err != nil {
+		t.Fatal(err)
+	}
+	if maxInFlight != 1 {
+		t.Errorf("got up to %d sub-resource requests at once, want 1", maxInFlight)
+	}
+}
@@ -0,0 +1,126 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is how many times a request failing with a 429, a 5xx
+	// or a transport error is retried before giving up.
+	DefaultMaxRetries = 5
+
+	// DefaultMaxWait caps any single pause between requests, whether it comes
+	// from backoff or from the rate limit headers.
+	DefaultMaxWait = time.Minute
+
+	// baseBackoff is the delay before the first retry, doubled each attempt.
+	baseBackoff = 500 * time.Millisecond
+)
+
+// rateLimiter pauses every request made by a Client while the account's rate
+// limit window is exhausted, based on the X-Rate-Limit-* response headers.
+type rateLimiter struct {
+	mu       sync.Mutex
+	resumeAt time.Time
+}
+
+// wait blocks until the current rate limit window allows another request, or
+// until ctx is done.
+func (r *rateLimiter) wait(ctx context.Context) error {
+	r.mu.Lock()
+	resumeAt := r.resumeAt
+	r.mu.Unlock()
+
+	return sleep(ctx, time.Until(resumeAt))
+}
+
+// update records the rate limit state reported by a response. When no
+// requests remain in the window, subsequent requests are held until the
+// window resets, but never for longer than maxWait.
+func (r *rateLimiter) update(header http.Header, maxWait time.Duration) {
+	remaining, err := strconv.Atoi(header.Get("X-Rate-Limit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+	reset, ok := rateLimitReset(header)
+	if !ok {
+		return
+	}
+	if limit := time.Now().Add(maxWait); reset.After(limit) {
+		reset = limit
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reset.After(r.resumeAt) {
+		r.resumeAt = reset
+	}
+}
+
+// rateLimitReset parses the X-Rate-Limit-Reset header, which holds the UTC
+// epoch second at which the current window ends.
+func rateLimitReset(header http.Header) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(header.Get("X-Rate-Limit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// retryable reports whether a failed request is worth another attempt.
+func retryable(err error) bool {
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the given retry attempt. It is
+// an exponential backoff with jitter, stretched to the end of the rate limit
+// window when the API reported one, and capped at maxWait.
+func retryDelay(attempt int, header http.Header, maxWait time.Duration) time.Duration {
+	backoff := baseBackoff << uint(attempt)
+	if backoff <= 0 || backoff > maxWait {
+		backoff = maxWait
+	}
+	delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	if header != nil {
+		if reset, ok := rateLimitReset(header); ok {
+			if untilReset := time.Until(reset); untilReset > delay {
+				delay = untilReset
+			}
+		}
+	}
+
+	if delay > maxWait {
+		delay = maxWait
+	}
+	return delay
+}
+
+// sleep pauses for d, returning early with ctx.Err() if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rateLimitHeader returns the X-Rate-Limit-* headers of a response that left
+// remaining requests in a window ending at reset.
+func rateLimitHeader(remaining int, reset time.Time) http.Header {
+	header := http.Header{}
+	header.Set("X-Rate-Limit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-Rate-Limit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return header
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining int
+		reset     time.Duration
+		maxWait   time.Duration
+		min, max  time.Duration
+	}{
+		{"requests left", 10, time.Hour, time.Minute, 0, 50 * time.Millisecond},
+		{"window reset", 0, -time.Hour, time.Minute, 0, 50 * time.Millisecond},
+		{"capped by maxWait", 0, time.Hour, 200 * time.Millisecond, 150 * time.Millisecond, time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var limiter rateLimiter
+			limiter.update(rateLimitHeader(test.remaining, time.Now().Add(test.reset)), test.maxWait)
+
+			start := time.Now()
+			if err := limiter.wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed < test.min || elapsed > test.max {
+				t.Errorf("waited %v, want between %v and %v", elapsed, test.min, test.max)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitCancelled(t *testing.T) {
+	var limiter rateLimiter
+	limiter.update(rateLimitHeader(0, time.Now().Add(time.Hour)), time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempt  int
+		header   http.Header
+		maxWait  time.Duration
+		min, max time.Duration
+	}{
+		{"first retry", 0, nil, time.Minute, 250 * time.Millisecond, 500 * time.Millisecond},
+		{"doubles", 3, nil, time.Minute, 2 * time.Second, 4 * time.Second},
+		{"capped backoff", 10, nil, 10 * time.Second, 5 * time.Second, 10 * time.Second},
+		{"overflowing backoff", 100, nil, 10 * time.Second, 5 * time.Second, 10 * time.Second},
+		{"until reset", 0, rateLimitHeader(0, time.Now().Add(30*time.Second)), time.Minute, 28 * time.Second, 30 * time.Second},
+		{"reset capped", 0, rateLimitHeader(0, time.Now().Add(time.Hour)), 10 * time.Second, 10 * time.Second, 10 * time.Second},
+		{"reset passed", 0, rateLimitHeader(0, time.Now().Add(-time.Hour)), time.Minute, 250 * time.Millisecond, 500 * time.Millisecond},
+		{"no wait", 3, nil, 0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if delay := retryDelay(test.attempt, test.header, test.maxWait); delay < test.min || delay > test.max {
+					t.Fatalf("got %v, want between %v and %v", delay, test.min, test.max)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		status   int
+		requests int32
+	}{
+		{http.StatusTooManyRequests, 3},
+		{http.StatusServiceUnavailable, 3},
+		{http.StatusNotFound, 1},
+		{http.StatusUnauthorized, 1},
+	}
+	for _, test := range tests {
+		t.Run(strconv.Itoa(test.status), func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, `{"err": 1, "message": "failed"}`)
+			}))
+			defer server.Close()
+
+			client := NewClient("token", WithBaseURL(server.URL), WithMaxRetries(2), WithMaxWait(0))
+			_, err := client.fetch(context.Background(), "projects")
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != test.status {
+				t.Fatalf("got %v, want a %d *StatusError", err, test.status)
+			}
+			if requests != test.requests {
+				t.Errorf("made %d requests, want %d", requests, test.requests)
+			}
+			if retries := client.Stats().Retries; retries != int(test.requests)-1 {
+				t.Errorf("counted %d retries, want %d", retries, test.requests-1)
+			}
+		})
+	}
+}
+
+func TestFetchPacesRequests(t *testing.T) {
+	// Every response exhausts the window, which resets long after maxWait.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Rate-Limit-Remaining", "0")
+		w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		fmt.Fprint(w, `{"err": 0, "result": []}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURL(server.URL), WithMaxWait(200*time.Millisecond))
+	start := time.Now()
+	if _, err := client.fetch(context.Background(), "projects"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= 150*time.Millisecond {
+		t.Fatalf("first request took %v, want no wait", elapsed)
+	}
+	if _, err := client.fetch(context.Background(), "teams"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("second request after %v, want it held until maxWait", elapsed)
+	}
+}
@@ -13,18 +13,60 @@ type AccessToken struct {
 	AccessToken          string   `json:"access_token"`
 	Name                 string   `json:"name"`
 	ProjectID            int      `json:"project_id"`
-	RateLimitWindowCount int      `json:"rate_limit_window_count",omitempty`
-	RateLimitWindowSize  int      `json:"rate_limit_window_size",omitempty`
+	RateLimitWindowCount int      `json:"rate_limit_window_count,omitempty"`
+	RateLimitWindowSize  int      `json:"rate_limit_window_size,omitempty"`
 	Scopes               []string `json:"scopes"`
+	Status               string   `json:"status"`
 	Token                string   `json:"token"`
 }
 
+type NotificationFilter struct {
+	Type      string `json:"type"`
+	Operation string `json:"operation,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Period    int    `json:"period,omitempty"`
+	Count     int    `json:"count,omitempty"`
+}
+
+type NotificationRule struct {
+	ID      int                    `json:"id"`
+	Channel string                 `json:"channel"`
+	Trigger string                 `json:"trigger"`
+	Filters []NotificationFilter   `json:"filters"`
+	Config  map[string]interface{} `json:"config"`
+}
+
+type Integration struct {
+	Channel  string                 `json:"channel"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+type Invitation struct {
+	ID      int    `json:"id"`
+	TeamID  int    `json:"team_id"`
+	ToEmail string `json:"to_email"`
+	Status  string `json:"status"`
+}
+
 type Project struct {
-	ID           int    `json:"id"`
-	AccountID    int    `json:"account_id"`
-	Name         string `json:name`
-	AccessTokens []AccessToken
+	ID            int    `json:"id"`
+	AccountID     int    `json:"account_id"`
+	Name          string `json:"name"`
+	AccessTokens  []AccessToken
+	Notifications []NotificationRule
+	Integrations  []Integration
+
+	// NoReadToken is set when the notification rules and integrations of the
+	// project were to be fetched but it has no enabled access token with the
+	// read scope to fetch them with.
+	NoReadToken bool `json:",omitempty"`
 }
+type ServiceLink struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
 type Team struct {
 	ID          int    `json:"id"`
 	AccountID   int    `json:"account_id"`
@@ -32,6 +74,7 @@ type Team struct {
 	Name        string `json:"name"`
 	Users       []int
 	Projects    []int
+	Invites     []Invitation
 }
 
 type TeamProjects struct {
@@ -57,13 +100,33 @@ type User struct {
  * All API responses contain an error code value and a result object that may
  * or may not contain a nested JSON object.
  */
+type envelope struct {
+	Err     int    `json:"err"`
+	Message string `json:"message"`
+}
+
 type accessTokenResponse struct {
 	Err    int           `json:"err"`
 	Result []AccessToken `json:"result"`
 }
 
+type integrationResponse struct {
+	Err    int                    `json:"err"`
+	Result map[string]interface{} `json:"result"`
+}
+
+type invitationResponse struct {
+	Err    int          `json:"err"`
+	Result []Invitation `json:"result"`
+}
+
+type notificationRulesResponse struct {
+	Err    int                `json:"err"`
+	Result []NotificationRule `json:"result"`
+}
+
 type projectResponse struct {
-	Err    int       `json:err`
+	Err    int       `json:"err"`
 	Result []Project `json:"result"`
 }
 
@@ -72,6 +135,11 @@ type projectTeamResponse struct {
 	Result []interface{} `json:"result"`
 }
 
+type serviceLinkResponse struct {
+	Err    int           `json:"err"`
+	Result []ServiceLink `json:"result"`
+}
+
 type teamResponse struct {
 	Err    int    `json:"err"`
 	Result []Team `json:"result"`
@@ -0,0 +1,53 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// WriteSnapshot. ReadSnapshot refuses snapshots of any other version.
+//
+// Version 2 added the Selection the account was fetched with. Version 1
+// snapshots do not say which parts of the account they hold, so they are
+// refused rather than taken for the whole account.
+const SnapshotVersion = 2
+
+// snapshot is the on-disk form of a fetched Account.
+type snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Selection Selection `json:"selection"`
+	Account   *Account  `json:"account"`
+}
+
+// WriteSnapshot writes the account fetched with sel to w as versioned JSON,
+// so that it can later be rendered again without querying the API.
+func WriteSnapshot(w io.Writer, account *Account, sel Selection) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Selection: sel,
+		Account:   account,
+	})
+}
+
+// ReadSnapshot reads an account previously written by WriteSnapshot, along
+// with the Selection it was fetched with.
+func ReadSnapshot(r io.Reader) (*Account, Selection, error) {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, Selection{}, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if s.Version != SnapshotVersion {
+		return nil, Selection{}, fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, SnapshotVersion)
+	}
+	if s.Account == nil {
+		return nil, Selection{}, fmt.Errorf("snapshot contains no account")
+	}
+	return s.Account, s.Selection, nil
+}
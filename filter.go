@@ -0,0 +1,314 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+// filter narrows the account down to the projects, teams and users to export.
+//
+// Every filter that is set must agree for a resource to be kept: it has to be
+// reachable from one of the fromTeams, match the include patterns of its type
+// and none of the exclude patterns.
+type filter struct {
+	includeProjects patterns
+	excludeProjects patterns
+	includeTeams    patterns
+	excludeTeams    patterns
+	includeUsers    patterns
+	excludeUsers    patterns
+	fromTeams       patterns
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filter {
+	f := &filter{}
+	fs.Var(&f.includeProjects, "includeProjects", "Only export the projects matching these IDs, name globs or /regexps/.")
+	fs.Var(&f.excludeProjects, "excludeProjects", "Do not export the projects matching these IDs, name globs or /regexps/.")
+	fs.Var(&f.includeTeams, "includeTeams", "Only export the teams matching these IDs, name globs or /regexps/.")
+	fs.Var(&f.excludeTeams, "excludeTeams", "Do not export the teams matching these IDs, name globs or /regexps/.")
+	fs.Var(&f.includeUsers, "includeUsers", "Only export the users matching these IDs, username or email globs or /regexps/.")
+	fs.Var(&f.excludeUsers, "excludeUsers", "Do not export the users matching these IDs, username or email globs or /regexps/.")
+	fs.Var(&f.fromTeams, "fromTeams", "Only export these teams and the projects and users that belong to them.")
+	return f
+}
+
+// empty reports whether the filter keeps the whole account.
+func (f *filter) empty() bool {
+	return f == nil || len(f.includeProjects)+len(f.excludeProjects)+
+		len(f.includeTeams)+len(f.excludeTeams)+
+		len(f.includeUsers)+len(f.excludeUsers)+
+		len(f.fromTeams) == 0
+}
+
+// apply returns the part of account the filter keeps.
+//
+// Projects keep all their access tokens, and teams only list the projects and
+// users that are kept. Kept projects and users may still belong to teams that
+// are not kept; see externalTeams.
+func (f *filter) apply(account *fetcher.Account) *fetcher.Account {
+	if f.empty() {
+		return account
+	}
+
+	// Work out what is reachable from the fromTeams first, if any.
+	fromProjects, fromUsers := map[int]bool{}, map[int]bool{}
+	fromTeams := map[int]bool{}
+	for _, team := range account.Teams {
+		if f.fromTeams.match(team.ID, team.Name) {
+			fromTeams[team.ID] = true
+			for _, id := range team.Projects {
+				fromProjects[id] = true
+			}
+			for _, id := range team.Users {
+				fromUsers[id] = true
+			}
+		}
+	}
+	reachable := func(ids map[int]bool, id int) bool {
+		return len(f.fromTeams) == 0 || ids[id]
+	}
+
+	// Service links belong to the account as a whole, so no filter applies
+	// to them.
+	kept := &fetcher.Account{ServiceLinks: account.ServiceLinks}
+	keptProjects, keptUsers := map[int]bool{}, map[int]bool{}
+
+	for _, project := range account.Projects {
+		if reachable(fromProjects, project.ID) && selected(f.includeProjects, f.excludeProjects, project.ID, project.Name) {
+			kept.Projects = append(kept.Projects, project)
+			keptProjects[project.ID] = true
+		}
+	}
+	for _, user := range account.Users {
+		if reachable(fromUsers, user.ID) && selected(f.includeUsers, f.excludeUsers, user.ID, user.Username, user.Email) {
+			kept.Users = append(kept.Users, user)
+			keptUsers[user.ID] = true
+		}
+	}
+	for _, team := range account.Teams {
+		if reachable(fromTeams, team.ID) && selected(f.includeTeams, f.excludeTeams, team.ID, team.Name) {
+			team.Projects = keepIDs(team.Projects, keptProjects)
+			team.Users = keepIDs(team.Users, keptUsers)
+			team.Invites = keepInvites(team.Invites, f)
+			kept.Teams = append(kept.Teams, team)
+		}
+	}
+	return kept
+}
+
+// externalTeams returns the teams that the projects and users of account
+// belong to but that are not among its teams, looking them up in teams.
+//
+// The writers read them through data sources, so that the memberships of
+// what is exported are left as they are. The exception are members, the
+// teams whose memberships are written as rollbar_team_user resources even
+// though the teams themselves are not exported, for lack of the teams type:
+// they keep their pending invitations, and are referenced by them.
+func externalTeams(teams []fetcher.Team, account *fetcher.Account, members []fetcher.Team) []fetcher.Team {
+	known := map[int]bool{}
+	for _, team := range account.Teams {
+		known[team.ID] = true
+	}
+	projects := map[int]bool{}
+	for _, project := range account.Projects {
+		projects[project.ID] = true
+	}
+	users := map[int]bool{}
+	for _, user := range account.Users {
+		users[user.ID] = true
+	}
+
+	referenced := map[int]bool{}
+	for _, team := range teams {
+		for _, id := range team.Projects {
+			if projects[id] {
+				referenced[team.ID] = true
+			}
+		}
+		for _, id := range team.Users {
+			if users[id] {
+				referenced[team.ID] = true
+			}
+		}
+	}
+	for _, user := range account.Users {
+		for _, team := range user.Teams {
+			referenced[team.ID] = true
+		}
+	}
+	memberTeams := map[int]fetcher.Team{}
+	for _, team := range members {
+		memberTeams[team.ID] = team
+		if len(team.Invites) > 0 {
+			referenced[team.ID] = true
+		}
+	}
+
+	// The pending invitations of teams that are not exported are left alone,
+	// like the teams themselves.
+	var external []fetcher.Team
+	for _, team := range teams {
+		if referenced[team.ID] && !known[team.ID] {
+			if member, ok := memberTeams[team.ID]; ok {
+				team = member
+			} else {
+				team.Invites = nil
+			}
+			external = append(external, team)
+			known[team.ID] = true
+		}
+	}
+	// A user may belong to a team that is missing from the team listing.
+	for _, user := range account.Users {
+		for _, team := range user.Teams {
+			if !known[team.ID] {
+				external = append(external, team)
+				known[team.ID] = true
+			}
+		}
+	}
+	return external
+}
+
+// selected reports whether a resource matches the include patterns, if any,
+// and none of the exclude patterns.
+func selected(include patterns, exclude patterns, id int, names ...string) bool {
+	if len(include) > 0 && !include.match(id, names...) {
+		return false
+	}
+	return !exclude.match(id, names...)
+}
+
+// keepInvites returns the invitations whose email address the user filters
+// select. Invitations belong to a team, so they are not subject to
+// -fromTeams.
+func keepInvites(invitations []fetcher.Invitation, f *filter) []fetcher.Invitation {
+	var kept []fetcher.Invitation
+	for _, invitation := range invitations {
+		if selected(f.includeUsers, f.excludeUsers, 0, invitation.ToEmail) {
+			kept = append(kept, invitation)
+		}
+	}
+	return kept
+}
+
+// keepIDs returns the IDs that are in keep.
+func keepIDs(ids []int, keep map[int]bool) []int {
+	var kept []int
+	for _, id := range ids {
+		if keep[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// patterns is the value of a repeatable filter flag. Each pattern is a
+// Rollbar ID, a glob in path.Match syntax or a regular expression between
+// slashes. Several patterns may be given at once, separated by commas.
+type patterns []pattern
+
+type pattern struct {
+	raw    string
+	id     int
+	glob   string
+	regexp *regexp.Regexp
+}
+
+func (p *patterns) String() string {
+	if p == nil {
+		return ""
+	}
+	values := make([]string, len(*p))
+	for i, pattern := range *p {
+		values[i] = pattern.raw
+	}
+	return strings.Join(values, ",")
+}
+
+func (p *patterns) Set(value string) error {
+	for _, raw := range splitPatterns(value) {
+		parsed := pattern{raw: raw}
+		switch {
+		case len(raw) > 1 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/"):
+			re, err := regexp.Compile(raw[1 : len(raw)-1])
+			if err != nil {
+				return fmt.Errorf("%q is not a valid regular expression: %v", raw, err)
+			}
+			parsed.regexp = re
+		default:
+			if id, err := strconv.Atoi(raw); err == nil {
+				parsed.id = id
+				break
+			}
+			if _, err := path.Match(raw, ""); err != nil {
+				return fmt.Errorf("%q is not a valid glob: %v", raw, err)
+			}
+			parsed.glob = raw
+		}
+		*p = append(*p, parsed)
+	}
+	return nil
+}
+
+// splitPatterns splits a comma-separated list of patterns. A regular
+// expression between slashes may contain commas itself, such as {1,2}, so it
+// runs up to the first slash that is followed by a comma or the end of value.
+func splitPatterns(value string) []string {
+	var raws []string
+	for value != "" {
+		value = strings.TrimLeft(value, " \t,")
+		if value == "" {
+			break
+		}
+		end := strings.Index(value, ",")
+		if strings.HasPrefix(value, "/") {
+			for i := 1; i < len(value); i++ {
+				if value[i] != '/' {
+					continue
+				}
+				rest := strings.TrimLeft(value[i+1:], " \t")
+				if rest == "" || rest[0] == ',' {
+					end = len(value) - len(rest)
+					break
+				}
+			}
+		}
+		if end < 0 {
+			end = len(value)
+		}
+		raws = append(raws, strings.TrimSpace(value[:end]))
+		value = value[end:]
+	}
+	return raws
+}
+
+// match reports whether any pattern matches the ID or one of the names of a
+// resource.
+func (p patterns) match(id int, names ...string) bool {
+	for _, pattern := range p {
+		if pattern.id != 0 && pattern.id == id {
+			return true
+		}
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			if pattern.regexp != nil && pattern.regexp.MatchString(name) {
+				return true
+			}
+			if pattern.glob != "" {
+				if ok, _ := path.Match(pattern.glob, name); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+	"github.com/rollbar/rollbar-terraform-importer/writer"
+)
+
+// apiFlags are the flags of every subcommand that may query the Rollbar API.
+type apiFlags struct {
+	accessToken     *string
+	accessTokenFile *string
+	apiURL          *string
+	timeout         *time.Duration
+	requestTimeout  *time.Duration
+	userAgent       *string
+	pageSize        *int
+	maxRetries      *int
+	concurrency     *int
+	maxWait         *time.Duration
+}
+
+func addAPIFlags(fs *flag.FlagSet) *apiFlags {
+	return &apiFlags{
+		accessToken:     fs.String("accessToken", "", "Rollbar account access token. Prefer -accessTokenFile or "+accessTokenEnv+", which keep it out of shell history."),
+		accessTokenFile: fs.String("accessTokenFile", "", "File to read the Rollbar account access token from (\"-\" reads it from stdin)."),
+		apiURL:          fs.String("apiURL", fetcher.DefaultBaseURL, "Base URL of the Rollbar API."),
+		timeout:         fs.Duration("timeout", 0, "Overall deadline for the import (0 means no deadline)."),
+		requestTimeout:  fs.Duration("requestTimeout", fetcher.DefaultTimeout, "Timeout for each Rollbar API request."),
+		userAgent:       fs.String("userAgent", fetcher.DefaultUserAgent, "User-Agent header sent to the Rollbar API."),
+		pageSize:        fs.Int("pageSize", 0, "Items to request per page from paginated listing endpoints (0 uses the API default)."),
+		maxRetries:      fs.Int("maxRetries", fetcher.DefaultMaxRetries, "Retries for rate-limited or failed Rollbar API requests."),
+		concurrency:     fs.Int("concurrency", fetcher.DefaultConcurrency, "Parallel per-project, per-team and per-user Rollbar API requests."),
+		maxWait:         fs.Duration("maxWait", fetcher.DefaultMaxWait, "Longest single pause between Rollbar API requests."),
+	}
+}
+
+// accessTokenEnv is the environment variable the access token is read from,
+// the same one the Rollbar Terraform provider uses.
+const accessTokenEnv = "ROLLBAR_ACCESS_TOKEN"
+
+// client validates the access token and builds a fetcher.Client from the
+// flags.
+func (f *apiFlags) client() (*fetcher.Client, error) {
+	accessToken, err := f.token(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure that an access token was provided one way or another.
+	if accessToken == "" {
+		return nil, &exitError{
+			code:  -1,
+			msg:   "A Rollbar access token must be provided with -accessToken, -accessTokenFile or " + accessTokenEnv + ".",
+			usage: true,
+		}
+	}
+	redact(accessToken)
+
+	// Validate the access token is actually an access token.
+	validate := validator.New()
+	if vErrs := validate.Var(accessToken, "required,alphanumunicode"); vErrs != nil {
+		return nil, &exitError{code: -1, msg: "Provided access token is not a valid access token."}
+	}
+
+	return fetcher.NewClient(accessToken,
+		fetcher.WithBaseURL(*f.apiURL),
+		fetcher.WithTimeout(*f.requestTimeout),
+		fetcher.WithUserAgent(*f.userAgent),
+		fetcher.WithPageSize(*f.pageSize),
+		fetcher.WithMaxRetries(*f.maxRetries),
+		fetcher.WithMaxWait(*f.maxWait),
+		fetcher.WithConcurrency(*f.concurrency),
+	), nil
+}
+
+// token returns the access token from, in order of precedence, the
+// -accessToken flag, the file named by -accessTokenFile (stdin for "-") and
+// the ROLLBAR_ACCESS_TOKEN environment variable. It returns an empty string
+// when none of them is set.
+func (f *apiFlags) token(stdin io.Reader) (string, error) {
+	if *f.accessToken != "" {
+		return *f.accessToken, nil
+	}
+
+	if *f.accessTokenFile != "" {
+		var data []byte
+		var err error
+		if *f.accessTokenFile == "-" {
+			data, err = ioutil.ReadAll(stdin)
+		} else {
+			data, err = ioutil.ReadFile(*f.accessTokenFile)
+		}
+		if err != nil {
+			return "", &exitError{code: -1, msg: fmt.Sprintf("Could not read the access token: %v", err)}
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return strings.TrimSpace(os.Getenv(accessTokenEnv)), nil
+}
+
+// context returns a context that is cancelled on Ctrl-C or SIGTERM, and once
+// the overall deadline passes.
+func (f *apiFlags) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *f.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, *f.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// outputFlags are the flags of every subcommand that renders Terraform files.
+type outputFlags struct {
+	singleFile   *bool
+	importBlocks *bool
+	outPath      *string
+	force        *bool
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	return &outputFlags{
+		singleFile:   fs.Bool("singleFile", false, "Write to a single Terraform file."),
+		importBlocks: fs.Bool("importBlocks", false, "Write Terraform 1.5+ import blocks to imports.tf instead of an import shell file."),
+		outPath:      fs.String("out", ".", "Output directory for generated files."),
+		force:        fs.Bool("force", false, "Replace generated files left by an earlier run."),
+	}
+}
+
+// validate checks that if any path, except the default was given, that it
+// actually exists.
+func (f *outputFlags) validate() error {
+	if _, err := os.Stat(*f.outPath); os.IsNotExist(err) {
+		return &exitError{code: -2, msg: "Invalid file path provided for output."}
+	}
+	return nil
+}
+
+// providerFlags are the flags that shape the provider boilerplate written by
+// generate.
+type providerFlags struct {
+	version          *string
+	terraformVersion *string
+	variables        *bool
+	backend          *string
+	backendConfig    keyValues
+}
+
+func addProviderFlags(fs *flag.FlagSet) *providerFlags {
+	f := &providerFlags{
+		version:          fs.String("providerVersion", writer.DefaultProviderVersion, "Version constraint of the Rollbar provider."),
+		terraformVersion: fs.String("terraformVersion", "", "Required Terraform version constraint (e.g. \">= 1.0\")."),
+		variables:        fs.Bool("providerVariables", false, "Configure the provider from the rollbar_api_key and rollbar_project_api_key variables."),
+		backend:          fs.String("backend", "", "Backend block to write: "+strings.Join(writer.Backends, ", ")+"."),
+		backendConfig:    keyValues{},
+	}
+	fs.Var(f.backendConfig, "backendConfig", "Backend attribute as key=value (e.g. bucket=terraform-state), or block.key=value for a nested block. May be repeated.")
+	return f
+}
+
+// validate checks that the backend, if any, is one the writer knows about.
+func (f *providerFlags) validate() error {
+	if *f.backend == "" {
+		if len(f.backendConfig) > 0 {
+			return &exitError{code: -1, msg: "-backendConfig requires -backend.", usage: true}
+		}
+		return nil
+	}
+	for _, backend := range writer.Backends {
+		if *f.backend == backend {
+			return nil
+		}
+	}
+	return &exitError{code: -1, msg: "Unsupported backend \"" + *f.backend + "\".", usage: true}
+}
+
+func (f *providerFlags) provider() writer.Provider {
+	return writer.Provider{
+		Version:          *f.version,
+		TerraformVersion: *f.terraformVersion,
+		Variables:        *f.variables,
+		Backend:          *f.backend,
+		BackendConfig:    f.backendConfig,
+	}
+}
+
+// keyValues is the value of a repeatable key=value flag. Several pairs may be
+// given at once, separated by commas.
+type keyValues map[string]string
+
+func (kv keyValues) String() string {
+	values := make([]string, 0, len(kv))
+	for key, value := range kv {
+		values = append(values, key+"="+value)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func (kv keyValues) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, found := cut(pair, "=")
+		if !found || key == "" {
+			return fmt.Errorf("%q is not of the form key=value", pair)
+		}
+		kv[key] = value
+	}
+	return nil
+}
+
+// addTeamUsersFlag registers -teamUsers, which switches team membership
+// from the team_ids of every rollbar_user to rollbar_team_user resources.
+func addTeamUsersFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("teamUsers", false, "Write team memberships, including pending invitations, as rollbar_team_user resources.")
+}
+
+// nameOverrides is the value of the repeatable -names flag, which overrides
+// the resource names of individual projects, teams and users as
+// type.id=name, e.g. project.123=website. Several overrides may be given at
+// once, separated by commas.
+type nameOverrides writer.Overrides
+
+func addNamesFlag(fs *flag.FlagSet) *nameOverrides {
+	overrides := &nameOverrides{}
+	fs.Var(overrides, "names", "Override a resource name, as type.id=name (e.g. project.123=website). May be repeated.")
+	return overrides
+}
+
+func (o *nameOverrides) String() string {
+	if o == nil {
+		return ""
+	}
+	var values []string
+	for kind, names := range map[string]map[int]string{"project": o.Projects, "team": o.Teams, "user": o.Users} {
+		for id, name := range names {
+			values = append(values, kind+"."+strconv.Itoa(id)+"="+name)
+		}
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func (o *nameOverrides) Set(value string) error {
+	for _, override := range strings.Split(value, ",") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+		resource, name, found := cut(override, "=")
+		kind, id, dotted := cut(resource, ".")
+		if !found || !dotted || name == "" {
+			return fmt.Errorf("%q is not of the form type.id=name", override)
+		}
+		resourceID, err := strconv.Atoi(id)
+		if err != nil {
+			return fmt.Errorf("%q does not have a numeric Rollbar ID", override)
+		}
+
+		var names *map[int]string
+		switch kind {
+		case "project":
+			names = &o.Projects
+		case "team":
+			names = &o.Teams
+		case "user":
+			names = &o.Users
+		default:
+			return fmt.Errorf("%q is not a project, team or user", override)
+		}
+		if *names == nil {
+			*names = map[int]string{}
+		}
+		(*names)[resourceID] = name
+	}
+	return nil
+}
+
+// cut slices s around the first instance of sep.
+func cut(s string, sep string) (before string, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// exitError is an error that maps to a specific exit code, such as invalid
+// command line arguments.
+type exitError struct {
+	code  int
+	msg   string
+	usage bool
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+	"github.com/rollbar/rollbar-terraform-importer/writer"
+)
+
+// options holds the user-defined flags that shape the generated files.
+type options struct {
+	provider     writer.Provider
+	singleFile   bool
+	importBlocks bool
+	importsOnly  bool
+	outPath      string
+	snapshotIn   string
+	snapshotOut  string
+	force        bool
+	names        writer.Overrides
+	filter       *filter
+	resources    *resources
+	teamUsers    bool
+	quiet        bool
+}
+
+// generate takes the values of the user-defined flags and uses them to define
+// how to generate the Terraform files.
+//
+// Existing files are only replaced when opts.force is set. The account is
+// fetched from the API, or read from a snapshot when one is given, and the
+// files are rendered to a staging directory. They are moved into place only
+// once all of them are complete, so an error or a cancelled ctx leaves the
+// output directory untouched.
+func generate(ctx context.Context, client *fetcher.Client, opts options) error {
+	out, err := writer.NewOutput(opts.outPath, outputFiles(opts), staleFiles(opts), opts.force)
+	if err != nil {
+		return err
+	}
+	defer out.Discard()
+
+	// Fetch the necessary data via the Rollbar API or a snapshot.
+	account, err := loadAccount(ctx, client, opts)
+	if err != nil {
+		return err
+	}
+
+	// Narrow the account down to what is to be exported.
+	filtered := opts.filter.apply(account)
+	if !opts.filter.empty() {
+		status(opts, color.FgWhite, "Exporting %d of %d projects, %d of %d teams and %d of %d users.",
+			len(filtered.Projects), len(account.Projects), len(filtered.Teams), len(account.Teams),
+			len(filtered.Users), len(account.Users))
+	}
+	if skipped := skippedProjects(filtered.Projects, opts); len(skipped) > 0 {
+		status(opts, color.FgYellow,
+			"Skipped the notification rules and integrations of %d projects without an enabled read access token: %s.",
+			len(skipped), strings.Join(skipped, ", "))
+	}
+	// Memberships are written for the teams the filters keep, even when the
+	// teams themselves are only read through data sources. Those of the
+	// teams the filters leave out are left alone.
+	var members []fetcher.Team
+	if writesTeamUsers(opts) {
+		members = filtered.Teams
+	}
+	filtered, dataProjects := opts.resources.apply(filtered)
+	external := externalTeams(account.Teams, filtered, members)
+
+	// Resource names are assigned once for the whole account, before it is
+	// narrowed down, so that resources, references and import commands all
+	// agree on them, and a resource gets the same name whichever subset of the
+	// account is exported. Projects and teams that are not exported but are
+	// still referenced are written as data sources.
+	names := writer.NewNames(account.Projects, account.Teams, account.Users, account.ServiceLinks, opts.names)
+	account = &fetcher.Account{
+		Projects:     append(append([]fetcher.Project(nil), filtered.Projects...), dataProjects...),
+		Teams:        append(append([]fetcher.Team(nil), filtered.Teams...), external...),
+		Users:        filtered.Users,
+		ServiceLinks: filtered.ServiceLinks,
+	}
+	for _, project := range dataProjects {
+		names.UseProjectDataSource(project)
+	}
+	for _, team := range external {
+		names.UseTeamDataSource(team)
+	}
+	if opts.teamUsers {
+		names.UseTeamUserResources()
+	}
+
+	if !opts.importsOnly {
+		if err := writeResources(out, names, account, members, opts); err != nil {
+			return err
+		}
+	}
+	if err := writeImports(out, names, account, members, opts); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return out.Commit()
+}
+
+// writeResources renders the account's resources, either to one file per
+// resource type or to a single file. Only the files of the selected resource
+// types are written, and team memberships only for the members teams.
+func writeResources(out *writer.Output, names *writer.Names, account *fetcher.Account, members []fetcher.Team, opts options) error {
+	projects, teams, users := account.Projects, account.Teams, account.Users
+	writes := opts.resources.writes
+
+	if opts.singleFile {
+		/*
+		 * If the user pases the single file flag, just append constantly to the same file.
+		 *
+		 * FIXME: This is clunky.
+		 */
+		if err := writer.WriteProviderBlocks(opts.provider, out.Path("rollbar_account.tf")); err != nil {
+			return err
+		}
+		if writes("notifications.tf") || writes("integrations.tf") {
+			if err := writer.WriteProjectProviders(names, projects, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("teams.tf") {
+			if err := writer.WriteTeams(names, teams, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("projects.tf") {
+			if err := writer.WriteProjects(names, projects, teams, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("access_tokens.tf") {
+			if err := writer.WriteProjectAccessTokens(names, projects, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("notifications.tf") {
+			if err := writer.WriteNotifications(names, projects, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("integrations.tf") {
+			if err := writer.WriteIntegrations(names, projects, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("users.tf") {
+			if err := writer.WriteUsers(names, users, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writesTeamUsers(opts) {
+			if err := writer.WriteTeamUsers(names, members, users, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		if writes("service_links.tf") {
+			if err := writer.WriteServiceLinks(names, account.ServiceLinks, out.Path("rollbar_account.tf")); err != nil {
+				return err
+			}
+		}
+		status(opts, color.FgGreen, "Rendered All Account Resources to rollbar_account.tf.")
+	} else {
+		if err := writer.WriteProviderBlocks(opts.provider, out.Path("main.tf")); err != nil {
+			return err
+		}
+		if writes("notifications.tf") || writes("integrations.tf") {
+			if err := writer.WriteProjectProviders(names, projects, out.Path("main.tf")); err != nil {
+				return err
+			}
+		}
+
+		if writes("teams.tf") {
+			if err := writer.WriteTeams(names, teams, out.Path("teams.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Team Resources to teams.tf.")
+		}
+		if writes("projects.tf") {
+			if err := writer.WriteProjects(names, projects, teams, out.Path("projects.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Project Resources to projects.tf.")
+		}
+		if writes("access_tokens.tf") {
+			if err := writer.WriteProjectAccessTokens(names, projects, out.Path("access_tokens.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Access Token Resources to access_tokens.tf.")
+		}
+		if writes("notifications.tf") {
+			if err := writer.WriteNotifications(names, projects, out.Path("notifications.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Notification Resources to notifications.tf.")
+		}
+		if writes("integrations.tf") {
+			if err := writer.WriteIntegrations(names, projects, out.Path("integrations.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Integration Resources to integrations.tf.")
+		}
+		if writes("users.tf") {
+			if err := writer.WriteUsers(names, users, out.Path("users.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered User Resources to users.tf.")
+		}
+		if writesTeamUsers(opts) {
+			if err := writer.WriteTeamUsers(names, members, users, out.Path("team_users.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Team Membership Resources to team_users.tf.")
+		}
+		if writes("service_links.tf") {
+			if err := writer.WriteServiceLinks(names, account.ServiceLinks, out.Path("service_links.tf")); err != nil {
+				return err
+			}
+			status(opts, color.FgGreen, "Rendered Service Link Resources to service_links.tf.")
+		}
+	}
+	return nil
+}
+
+// writeImports renders the import commands, or the import blocks, for every
+// resource of the account. Types that are not exported have been dropped from
+// the account, and data sources are never imported. Team memberships are only
+// imported for the members teams.
+func writeImports(out *writer.Output, names *writer.Names, account *fetcher.Account, members []fetcher.Team, opts options) error {
+	projects, teams, users := account.Projects, account.Teams, account.Users
+
+	if opts.importBlocks {
+		// The import IDs of access tokens are the tokens themselves, so they
+		// are kept out of imports.tf, which is meant to be committed.
+		if writesAccessTokenImports(opts) {
+			if err := writer.WriteProjectAccessTokenImportBlocks(names, projects, out.Path("access_token_imports.tf")); err != nil {
+				return err
+			}
+		}
+		if err := writer.WriteProjectImportBlocks(names, projects, out.Path("imports.tf")); err != nil {
+			return err
+		}
+		if err := writer.WriteNotificationImportBlocks(names, projects, out.Path("imports.tf")); err != nil {
+			return err
+		}
+		if err := writer.WriteIntegrationImportBlocks(names, projects, out.Path("imports.tf")); err != nil {
+			return err
+		}
+		if err := writer.WriteTeamImportBlocks(names, teams, out.Path("imports.tf")); err != nil {
+			return err
+		}
+		if err := writer.WriteUserImportBlocks(names, users, out.Path("imports.tf")); err != nil {
+			return err
+		}
+		if writesTeamUsers(opts) {
+			if err := writer.WriteTeamUserImportBlocks(names, members, users, out.Path("imports.tf")); err != nil {
+				return err
+			}
+		}
+		if err := writer.WriteServiceLinkImportBlocks(names, account.ServiceLinks, out.Path("imports.tf")); err != nil {
+			return err
+		}
+		status(opts, color.FgWhite, "Rendered Terraform Import Blocks to imports.tf")
+	} else {
+		if err := writer.WriteProjectAccessTokenImportCommands(names, projects, out.Path("import")); err != nil {
+			return err
+		}
+		if err := writer.WriteProjectImportCommands(names, projects, out.Path("import")); err != nil {
+			return err
+		}
+		if err := writer.WriteNotificationImportCommands(names, projects, out.Path("import")); err != nil {
+			return err
+		}
+		if err := writer.WriteIntegrationImportCommands(names, projects, out.Path("import")); err != nil {
+			return err
+		}
+		if err := writer.WriteTeamImportCommands(names, teams, out.Path("import")); err != nil {
+			return err
+		}
+		if err := writer.WriteUserImportCommands(names, users, out.Path("import")); err != nil {
+			return err
+		}
+		if writesTeamUsers(opts) {
+			if err := writer.WriteTeamUserImportCommands(names, members, users, out.Path("import")); err != nil {
+				return err
+			}
+		}
+		if err := writer.WriteServiceLinkImportCommands(names, account.ServiceLinks, out.Path("import")); err != nil {
+			return err
+		}
+		status(opts, color.FgWhite, "Rendered Terraform Import Commands to import")
+	}
+	return nil
+}
+
+// skippedProjects returns the names of the projects whose notification rules
+// and integrations are exported but could not be fetched, for lack of a
+// read access token.
+func skippedProjects(projects []fetcher.Project, opts options) []string {
+	if !opts.resources.writes("notifications.tf") && !opts.resources.writes("integrations.tf") {
+		return nil
+	}
+	var skipped []string
+	for _, project := range projects {
+		if project.NoReadToken {
+			skipped = append(skipped, project.Name)
+		}
+	}
+	return skipped
+}
+
+// writesAccessTokenImports reports whether the import blocks of access
+// tokens are written to access_token_imports.tf.
+func writesAccessTokenImports(opts options) bool {
+	return opts.importBlocks && opts.resources.writes("access_tokens.tf")
+}
+
+// writesTeamUsers reports whether team memberships are written as
+// rollbar_team_user resources. They come with the users, whose team_ids they
+// replace.
+func writesTeamUsers(opts options) bool {
+	return opts.teamUsers && opts.resources.writes("users.tf")
+}
+
+// status prints a progress message in the given color, unless the run is
+// quiet.
+func status(opts options, attribute color.Attribute, format string, args ...interface{}) {
+	if opts.quiet {
+		return
+	}
+	color.New(attribute).Add(color.Bold).Fprintln(os.Stdout, redacted(fmt.Sprintf(format, args...)))
+}
+
+// outputFiles lists every file generate() writes for the chosen layout.
+func outputFiles(opts options) []string {
+	var names []string
+	switch {
+	case opts.importsOnly:
+		// Only the import commands or blocks are written.
+	case opts.singleFile:
+		names = []string{"rollbar_account.tf"}
+	default:
+		names = append([]string{"main.tf"}, opts.resources.files()...)
+		if writesTeamUsers(opts) {
+			names = append(names, "team_users.tf")
+		}
+	}
+	if opts.importBlocks {
+		names = append(names, "imports.tf")
+		if writesAccessTokenImports(opts) {
+			names = append(names, "access_token_imports.tf")
+		}
+	} else {
+		names = append(names, "import")
+	}
+	return names
+}
+
+// staleFiles returns the files the importer generates with other options than
+// opts, such as rollbar_account.tf next to the per-type files or import next
+// to imports.tf. Left in place, they would declare or import every resource a
+// second time. The imports subcommand only replaces the import commands or
+// blocks, so the resource files are kept then.
+func staleFiles(opts options) []string {
+	candidates := []string{"import", "imports.tf", "access_token_imports.tf"}
+	if !opts.importsOnly {
+		candidates = append(candidates, "main.tf", "rollbar_account.tf", "team_users.tf")
+		candidates = append(candidates, (*resources)(nil).files()...)
+	}
+
+	current := map[string]bool{}
+	for _, name := range outputFiles(opts) {
+		current[name] = true
+	}
+	var stale []string
+	for _, name := range candidates {
+		if !current[name] {
+			stale = append(stale, name)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,866 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+	"github.com/rollbar/rollbar-terraform-importer/rollbartest"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const testAccessToken = "0123456789abcdef0123456789abcdef"
+
+// webReadToken is the read access token of the web project in
+// testdata/account.json.
+const webReadToken = "d00dd00dd00dd00dd00dd00dd00dd00d"
+
+// newTestServer starts a fake Rollbar API serving testdata/account.json and
+// returns it along with a client pointed at it.
+func newTestServer(t *testing.T) (*rollbartest.Server, *fetcher.Client) {
+	t.Helper()
+
+	fixture, err := rollbartest.LoadFixture(filepath.Join("testdata", "account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rollbartest.NewServer(fixture)
+	server.AccessToken = testAccessToken
+	t.Cleanup(server.Close)
+
+	client := fetcher.NewClient(testAccessToken,
+		fetcher.WithBaseURL(server.URL()),
+		fetcher.WithMaxWait(0),
+	)
+	return server, client
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		golden string
+		opts   options
+	}{
+		{golden: "per_type", opts: options{}},
+		{golden: "single_file", opts: options{singleFile: true}},
+		{golden: "import_blocks", opts: options{importBlocks: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			_, client := newTestServer(t)
+			tt.opts.outPath = t.TempDir()
+
+			if err := generate(context.Background(), client, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			assertGoldenDir(t, tt.opts.outPath, filepath.Join("testdata", tt.golden))
+		})
+	}
+}
+
+func TestGeneratePaginatesAndRetries(t *testing.T) {
+	server, client := newTestServer(t)
+	server.PageSize = 1
+	server.RateLimitNext("users", 1)
+	server.FailNext("team/*/projects", 1, 503, "Service unavailable")
+
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "per_type"))
+
+	if stats := client.Stats(); stats.Users != 3 || stats.Retries != 2 {
+		t.Errorf("got %d users and %d retries, want 3 users and 2 retries", stats.Users, stats.Retries)
+	}
+}
+
+func TestGenerateRequestsEachPageOnce(t *testing.T) {
+	server, client := newTestServer(t)
+
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only users, team users and team invites are paginated, and end on an
+	// empty page, so no page is requested twice and the other listings take
+	// a single request.
+	seen := map[string]bool{}
+	for _, request := range server.Requests() {
+		if seen[request] {
+			t.Errorf("requested %s more than once", request)
+		}
+		seen[request] = true
+
+		endpoint := strings.SplitN(request, "?", 2)[0]
+		paginated := endpoint == "users" || strings.HasSuffix(endpoint, "/users") || strings.HasSuffix(endpoint, "/invites")
+		if paginated != strings.Contains(request, "page=") {
+			t.Errorf("requested %s, want page parameters only on paginated listings", request)
+		}
+	}
+
+	// Only the web project has an enabled read token, so its notifications
+	// are the only ones requested.
+	tokens := append(server.AccessTokens("notifications/*"), server.AccessTokens("notifications/*/rules")...)
+	if len(tokens) == 0 {
+		t.Error("no notifications were requested")
+	}
+	for _, token := range tokens {
+		if token != webReadToken {
+			t.Errorf("requested notifications with token %s, want the web project's read token", token)
+		}
+	}
+}
+
+func TestGenerateAPIError(t *testing.T) {
+	server, client := newTestServer(t)
+	server.FailNext("teams", 1, 403, "insufficient privileges")
+
+	outPath := t.TempDir()
+	err := generate(context.Background(), client, options{outPath: outPath})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if code := exitCode(err); code != 4 {
+		t.Errorf("got exit code %d for %v, want 4", code, err)
+	}
+
+	files, _ := ioutil.ReadDir(outPath)
+	if len(files) != 0 {
+		t.Errorf("expected no files to be written, found %d", len(files))
+	}
+}
+
+func TestGenerateErrorExitCodes(t *testing.T) {
+	// closed is an API that cannot be reached at all.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	malformed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"err": 0, "result": [`))
+	}))
+	t.Cleanup(malformed.Close)
+
+	tests := []struct {
+		name   string
+		client func(t *testing.T) *fetcher.Client
+		code   int
+		target interface{}
+	}{
+		{
+			name: "transport",
+			client: func(t *testing.T) *fetcher.Client {
+				return fetcher.NewClient(testAccessToken, fetcher.WithBaseURL(closed.URL), fetcher.WithMaxRetries(0), fetcher.WithMaxWait(0))
+			},
+			code:   3,
+			target: new(*fetcher.TransportError),
+		},
+		{
+			name: "api",
+			client: func(t *testing.T) *fetcher.Client {
+				server, client := newTestServer(t)
+				server.FailNext("teams", 1, http.StatusOK, "insufficient privileges")
+				return client
+			},
+			code:   5,
+			target: new(*fetcher.APIError),
+		},
+		{
+			name: "decode",
+			client: func(t *testing.T) *fetcher.Client {
+				return fetcher.NewClient(testAccessToken, fetcher.WithBaseURL(malformed.URL), fetcher.WithMaxWait(0))
+			},
+			code:   6,
+			target: new(*fetcher.DecodeError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outPath := t.TempDir()
+			err := generate(context.Background(), tt.client(t), options{outPath: outPath, quiet: true})
+			if !errors.As(err, tt.target) {
+				t.Fatalf("got %v, want a %T", err, tt.target)
+			}
+			if code := exitCode(err); code != tt.code {
+				t.Errorf("got exit code %d for %v, want %d", code, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestGenerateInterrupted(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "cancelled", code: 130},
+		{name: "timeout", args: []string{"-timeout", "100ms"}, code: 124},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t)
+			// The rate-limited request is retried after a backoff of at least
+			// 250ms, during which the run is interrupted.
+			server.RateLimitNext("team/*/projects", 1)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			api := addAPIFlags(fs)
+			args := append([]string{"-accessToken", testAccessToken, "-apiURL", server.URL()}, tt.args...)
+			if err := fs.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+			client, err := api.client()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := api.context()
+			defer cancel()
+			if tt.code == 130 {
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+
+			outPath := t.TempDir()
+			err = generate(ctx, client, options{outPath: outPath, quiet: true})
+			if code := exitCode(err); code != tt.code {
+				t.Errorf("got exit code %d for %v, want %d", code, err, tt.code)
+			}
+
+			if !requested(server, "team/*/projects") {
+				t.Error("interrupted before any team's projects were requested")
+			}
+			// Neither the output files nor the staging directory are left.
+			if files := listFiles(t, outPath); len(files) != 0 {
+				t.Errorf("expected no files to be written, found %v", files)
+			}
+		})
+	}
+}
+
+func TestGenerateFromTeam(t *testing.T) {
+	_, client := newTestServer(t)
+
+	filter := &filter{}
+	if err := filter.fromTeams.Set("Frontend"); err != nil {
+		t.Fatal(err)
+	}
+	if err := filter.excludeUsers.Set("/^carol/"); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath, filter: filter}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "from_team"))
+}
+
+func TestGenerateNamesIgnoreFilters(t *testing.T) {
+	// "My App" and "My.App" collide, so both are suffixed with their ID, even
+	// when only one of them is exported.
+	server := rollbartest.NewServer(rollbartest.Fixture{
+		Projects: []fetcher.Project{{ID: 1, Name: "My App"}, {ID: 2, Name: "My.App"}},
+		Teams: []fetcher.Team{
+			{ID: 3, Name: "Web", AccessLevel: "standard", Projects: []int{1}},
+			{ID: 4, Name: "Mobile", AccessLevel: "standard", Projects: []int{2}},
+		},
+	})
+	t.Cleanup(server.Close)
+	client := fetcher.NewClient(testAccessToken, fetcher.WithBaseURL(server.URL()), fetcher.WithMaxWait(0))
+
+	fromWeb := &filter{}
+	if err := fromWeb.fromTeams.Set("Web"); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []*filter{nil, fromWeb} {
+		outPath := t.TempDir()
+		if err := generate(context.Background(), client, options{outPath: outPath, filter: f, quiet: true}); err != nil {
+			t.Fatal(err)
+		}
+		projects, err := ioutil.ReadFile(filepath.Join(outPath, "projects.tf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(projects), `resource "rollbar_project" "My_App_1"`) {
+			t.Errorf("project 1 is not named My_App_1 with filter %v:\n%s", f, projects)
+		}
+	}
+}
+
+func TestGenerateAccessTokensOnly(t *testing.T) {
+	server, client := newTestServer(t)
+
+	resources := &resources{}
+	if err := resources.Set("access_tokens"); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath, resources: resources}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "access_tokens_only"))
+
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, "user") || strings.HasPrefix(request, "team") {
+			t.Errorf("requested %s, which is not needed for access tokens", request)
+		}
+	}
+}
+
+func TestGenerateTeamUsers(t *testing.T) {
+	server, client := newTestServer(t)
+
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath, teamUsers: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "team_users"))
+
+	// Invitations are only fetched when memberships are written as
+	// rollbar_team_user resources.
+	outPath = t.TempDir()
+	before := len(server.Requests())
+	if err := generate(context.Background(), client, options{outPath: outPath}); err != nil {
+		t.Fatal(err)
+	}
+	for _, request := range server.Requests()[before:] {
+		if strings.Contains(request, "/invites") {
+			t.Errorf("requested %s without -teamUsers", request)
+		}
+	}
+}
+
+func TestGenerateTeamUsersFiltered(t *testing.T) {
+	_, client := newTestServer(t)
+
+	// Bob also belongs to Backend, which is read through a data source and
+	// whose memberships are left alone.
+	filter := &filter{}
+	if err := filter.includeTeams.Set("Frontend"); err != nil {
+		t.Fatal(err)
+	}
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath, filter: filter, teamUsers: true, quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertMemberships(t, outPath, "Frontend_alice", "Frontend_bob", "Frontend_dave_example_com")
+}
+
+func TestGenerateTeamUsersWithoutTeams(t *testing.T) {
+	server, client := newTestServer(t)
+
+	// The teams are only read through data sources, but their memberships are
+	// written along with the users, pending invitations included.
+	resources := &resources{}
+	if err := resources.Set("users"); err != nil {
+		t.Fatal(err)
+	}
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath, resources: resources, teamUsers: true, quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertMemberships(t, outPath, "Owners_alice", "Frontend_alice", "Frontend_bob", "Frontend_dave_example_com", "Backend_bob")
+
+	teamUsers, err := ioutil.ReadFile(filepath.Join(outPath, "team_users.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(teamUsers), "team_id = data.rollbar_team.Frontend.id") {
+		t.Errorf("memberships do not reference the Frontend data source:\n%s", teamUsers)
+	}
+	invites := 0
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "/invites") {
+			invites++
+		}
+	}
+	if invites == 0 {
+		t.Error("invitations were not requested")
+	}
+}
+
+// assertMemberships checks that the rollbar_team_user resources in dir, and
+// their import commands, are exactly the named ones.
+func assertMemberships(t *testing.T, dir string, want ...string) {
+	t.Helper()
+
+	for _, file := range []string{"team_users.tf", "import"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(string(data), "\n") {
+			switch {
+			case strings.HasPrefix(line, `resource "rollbar_team_user" "`):
+				got = append(got, strings.Split(line, `"`)[3])
+			case strings.HasPrefix(line, "terraform import rollbar_team_user."):
+				got = append(got, strings.Fields(strings.TrimPrefix(line, "terraform import rollbar_team_user."))[0])
+			}
+		}
+		if !equalStrings(got, want) {
+			t.Errorf("got memberships %v in %s, want %v", got, file, want)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		value string
+		raws  []string
+		match string
+	}{
+		{value: "web-*, 12", raws: []string{"web-*", "12"}, match: "web-eu"},
+		{value: "/^api-(eu|us){1,2}$/", raws: []string{"/^api-(eu|us){1,2}$/"}, match: "api-euus"},
+		{value: "/^api-(eu|us){1,2}$/ ,web,/a/b/", raws: []string{"/^api-(eu|us){1,2}$/", "web", "/a/b/"}, match: "a/b"},
+	}
+
+	for _, tt := range tests {
+		var p patterns
+		if err := p.Set(tt.value); err != nil {
+			t.Errorf("Set(%q): %v", tt.value, err)
+			continue
+		}
+		var got []string
+		for _, pattern := range p {
+			got = append(got, pattern.raw)
+		}
+		if !equalStrings(got, tt.raws) {
+			t.Errorf("Set(%q) parsed %q, want %q", tt.value, got, tt.raws)
+		}
+		if !p.match(0, tt.match) {
+			t.Errorf("patterns %q do not match %q", tt.value, tt.match)
+		}
+	}
+}
+
+func TestSkippedProjects(t *testing.T) {
+	_, client := newTestServer(t)
+
+	account, err := client.FetchAccount(context.Background(), fetcher.SelectAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The read token of the api project is disabled.
+	if got := skippedProjects(account.Projects, options{}); !equalStrings(got, []string{"api"}) {
+		t.Errorf("got skipped projects %v, want [api]", got)
+	}
+
+	resources := &resources{}
+	if err := resources.Set("projects,access_tokens"); err != nil {
+		t.Fatal(err)
+	}
+	if got := skippedProjects(account.Projects, options{resources: resources}); len(got) != 0 {
+		t.Errorf("got skipped projects %v without exporting notifications", got)
+	}
+}
+
+func TestGenerateRefusesToOverwrite(t *testing.T) {
+	_, client := newTestServer(t)
+
+	outPath := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: outPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := generate(context.Background(), client, options{outPath: outPath})
+	if code := exitCode(err); code != 7 {
+		t.Errorf("got exit code %d for %v, want 7", code, err)
+	}
+
+	// Forcing the run replaces the files instead of appending to them.
+	if err := generate(context.Background(), client, options{outPath: outPath, force: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "per_type"))
+
+	// Switching layouts also replaces the files of the other layout, which
+	// would otherwise declare or import every resource a second time.
+	err = generate(context.Background(), client, options{outPath: outPath, singleFile: true})
+	if code := exitCode(err); code != 7 {
+		t.Errorf("got exit code %d for %v, want 7", code, err)
+	}
+	if err := generate(context.Background(), client, options{outPath: outPath, singleFile: true, force: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "single_file"))
+	if err := generate(context.Background(), client, options{outPath: outPath, importBlocks: true, force: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenDir(t, outPath, filepath.Join("testdata", "import_blocks"))
+}
+
+func TestGenerateSnapshotRoundTrip(t *testing.T) {
+	server, client := newTestServer(t)
+
+	snapshotPath := filepath.Join(t.TempDir(), "account.json")
+	outPath := t.TempDir()
+	opts := options{outPath: outPath, snapshotOut: snapshotPath}
+	if err := generate(context.Background(), client, opts); err != nil {
+		t.Fatal(err)
+	}
+	requests := len(server.Requests())
+
+	// The snapshot holds access tokens, so only its owner may read it.
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("snapshot has mode %v, want 0600", mode)
+	}
+
+	// Rendering from the snapshot must not touch the API, so no client is
+	// passed at all.
+	replayPath := t.TempDir()
+	opts = options{outPath: replayPath, snapshotIn: snapshotPath}
+	if err := generate(context.Background(), nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(server.Requests()); got != requests {
+		t.Errorf("rendering from a snapshot made %d requests", got-requests)
+	}
+	assertGoldenDir(t, replayPath, filepath.Join("testdata", "per_type"))
+}
+
+func TestGenerateSnapshotMissingParts(t *testing.T) {
+	_, client := newTestServer(t)
+
+	projectsOnly := &resources{}
+	if err := projectsOnly.Set("projects"); err != nil {
+		t.Fatal(err)
+	}
+	partialPath := filepath.Join(t.TempDir(), "projects.json")
+	if _, err := loadAccount(context.Background(), client, options{snapshotOut: partialPath, resources: projectsOnly, quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	fullPath := filepath.Join(t.TempDir(), "account.json")
+	if _, err := loadAccount(context.Background(), client, options{snapshotOut: fullPath, quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    options
+		missing string
+	}{
+		{name: "resources", opts: options{snapshotIn: partialPath}, missing: "project access tokens"},
+		{name: "teamUsers", opts: options{snapshotIn: fullPath, teamUsers: true}, missing: "team invitations"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.outPath = t.TempDir()
+			err := generate(context.Background(), nil, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.missing) {
+				t.Errorf("got %v, want an error about the missing %s", err, tt.missing)
+			}
+		})
+	}
+
+	// A snapshot of projects is enough to render projects alone.
+	opts := options{outPath: t.TempDir(), snapshotIn: partialPath, resources: projectsOnly, quiet: true}
+	if err := generate(context.Background(), nil, opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateImportsOnly(t *testing.T) {
+	_, client := newTestServer(t)
+
+	outPath := t.TempDir()
+	opts := options{outPath: outPath, importBlocks: true, importsOnly: true}
+	if err := generate(context.Background(), client, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := listFiles(t, outPath); !equalStrings(got, []string{"access_token_imports.tf", "imports.tf"}) {
+		t.Errorf("generated files %v, want only access_token_imports.tf and imports.tf", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	_, client := newTestServer(t)
+
+	dir := t.TempDir()
+	if err := generate(context.Background(), client, options{outPath: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	differences, err := diff(context.Background(), client, dir, options{}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if differences != 0 {
+		t.Fatalf("expected no differences right after generating, got:\n%s", out.String())
+	}
+
+	// Drop a team and rename another one in the existing configuration.
+	teams := `resource "rollbar_team" "Frontend" {
+  name         = "Web"
+  access_level = "standard"
+}
+
+resource "rollbar_team" "Legacy" {
+  name = "Legacy"
+}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "teams.tf"), []byte(teams), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	differences, err = diff(context.Background(), client, dir, options{}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "+ data.rollbar_team.Owners\n+ rollbar_team.Backend\n~ rollbar_team.Frontend\n- rollbar_team.Legacy\n"
+	if differences != 4 || out.String() != want {
+		t.Errorf("got %d differences:\n%s\nwant 4:\n%s", differences, out.String(), want)
+	}
+}
+
+func TestAccessTokenPrecedence(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("fromfile\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   string
+		stdin string
+		want  string
+	}{
+		{name: "flag wins", args: []string{"-accessToken", "fromflag", "-accessTokenFile", tokenFile}, env: "fromenv", want: "fromflag"},
+		{name: "file over env", args: []string{"-accessTokenFile", tokenFile}, env: "fromenv", want: "fromfile"},
+		{name: "stdin", args: []string{"-accessTokenFile", "-"}, env: "fromenv", stdin: " fromstdin\n", want: "fromstdin"},
+		{name: "env", env: "fromenv", want: "fromenv"},
+		{name: "none", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, accessTokenEnv, tt.env)
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			api := addAPIFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := api.token(strings.NewReader(tt.stdin))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got token %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	redact(testAccessToken)
+
+	got := redacted("request with token " + testAccessToken + " failed")
+	if want := "request with token [REDACTED] failed"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "importer.hcl")
+	settings := `
+out         = "terraform"
+single_file = true
+page_size   = 50
+names       = { "project.10" = "website", "team.2" = "web" }
+dir         = "ignored by generate"
+`
+	if err := ioutil.WriteFile(config, []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	setupGenerate(fs)
+	addConfigFlag(fs)
+	if err := fs.Parse([]string{"-config", config, "-pageSize", "10"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"out":        "terraform",
+		"singleFile": "true",
+		"pageSize":   "10",
+		"names":      "project.10=website,team.2=web",
+	}
+	for name, value := range want {
+		if got := fs.Lookup(name).Value.String(); got != value {
+			t.Errorf("-%s is %q, want %q", name, got, value)
+		}
+	}
+
+	tests := []struct {
+		settings string
+		err      string
+	}{
+		{settings: `outPath = "terraform"`, err: `unknown setting "outPath"`},
+		{settings: `backend_config = { workspaces = { name = ["a", "b"] } }`, err: "workspaces.name must not be a list"},
+	}
+	for _, tt := range tests {
+		if err := ioutil.WriteFile(config, []byte(tt.settings), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fs = flag.NewFlagSet("generate", flag.ContinueOnError)
+		setupGenerate(fs)
+		addConfigFlag(fs)
+		fs.Parse([]string{"-config", config})
+		if err := loadConfig(fs); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("got %v for %s, want an error containing %q", err, tt.settings, tt.err)
+		}
+	}
+}
+
+func TestLoadConfigNestedMap(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "importer.hcl")
+	settings := `
+backend = "remote"
+backend_config = {
+  organization = "example"
+  workspaces   = { name = "rollbar" }
+}
+`
+	if err := ioutil.WriteFile(config, []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	setupGenerate(fs)
+	addConfigFlag(fs)
+	if err := fs.Parse([]string{"-config", config}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "organization=example,workspaces.name=rollbar"
+	if got := fs.Lookup("backendConfig").Value.String(); got != want {
+		t.Errorf("-backendConfig is %q, want %q", got, want)
+	}
+}
+
+// setenv sets an environment variable for the duration of the test.
+func setenv(t *testing.T, key string, value string) {
+	t.Helper()
+
+	previous, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// assertGoldenDir compares every file generated into dir with the file of the
+// same name in golden, or replaces the golden directory when the tests run
+// with -update.
+func assertGoldenDir(t *testing.T, dir string, golden string) {
+	t.Helper()
+
+	if *update {
+		if err := os.RemoveAll(golden); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(golden, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := listFiles(t, dir)
+	if !*update {
+		if want := listFiles(t, golden); !equalStrings(got, want) {
+			t.Fatalf("generated files %v, want %v", got, want)
+		}
+	}
+
+	for _, name := range got {
+		gotData, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		goldenPath := filepath.Join(golden, name)
+		if *update {
+			if err := ioutil.WriteFile(goldenPath, gotData, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		wantData, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gotData) != string(wantData) {
+			t.Errorf("%s does not match %s\n--- got ---\n%s\n--- want ---\n%s", name, goldenPath, gotData, wantData)
+		}
+	}
+}
+
+// requested reports whether the server received a request for an endpoint
+// matching pattern.
+func requested(server *rollbartest.Server, pattern string) bool {
+	for _, request := range server.Requests() {
+		if ok, _ := path.Match(pattern, strings.SplitN(request, "?", 2)[0]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// secrets are the values that must never show up in the importer's output,
+// such as the account access token.
+var secrets struct {
+	mu     sync.Mutex
+	values []string
+}
+
+// redact registers a secret to be masked by redacted from now on.
+func redact(secret string) {
+	if secret == "" {
+		return
+	}
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	secrets.values = append(secrets.values, secret)
+}
+
+// redacted masks every registered secret in s. All log lines and error
+// messages are passed through it before they are printed.
+func redacted(s string) string {
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	for _, secret := range secrets.values {
+		s = strings.ReplaceAll(s, secret, "[REDACTED]")
+	}
+	return s
+}
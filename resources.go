@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+// resourceTypes are the values the -resources flag accepts, in the order
+// they are listed.
+var resourceTypes = []string{"projects", "access_tokens", "notifications", "integrations", "teams", "users", "service_links"}
+
+// resources is the value of the -resources flag: the resource types to
+// export. A nil *resources exports every type.
+type resources struct {
+	projects      bool
+	accessTokens  bool
+	notifications bool
+	integrations  bool
+	teams         bool
+	users         bool
+	serviceLinks  bool
+
+	set bool
+}
+
+func addResourcesFlag(fs *flag.FlagSet) *resources {
+	r := &resources{projects: true, accessTokens: true, notifications: true, integrations: true, teams: true, users: true, serviceLinks: true}
+	fs.Var(r, "resources", "Comma-separated resource types to export: "+strings.Join(resourceTypes, ", ")+".")
+	return r
+}
+
+func (r *resources) String() string {
+	if r == nil {
+		return strings.Join(resourceTypes, ",")
+	}
+	var types []string
+	for _, resourceType := range resourceTypes {
+		if *r.field(resourceType) {
+			types = append(types, resourceType)
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+// Set replaces the default of every type with the given comma-separated
+// types, and adds to them when the flag is repeated.
+func (r *resources) Set(value string) error {
+	if !r.set {
+		*r = resources{set: true}
+	}
+	for _, resourceType := range strings.Split(value, ",") {
+		resourceType = strings.TrimSpace(resourceType)
+		if resourceType == "" {
+			continue
+		}
+		field := r.field(resourceType)
+		if field == nil {
+			return fmt.Errorf("unknown resource type %q, must be one of %s", resourceType, strings.Join(resourceTypes, ", "))
+		}
+		*field = true
+	}
+	return nil
+}
+
+func (r *resources) field(resourceType string) *bool {
+	switch resourceType {
+	case "projects":
+		return &r.projects
+	case "access_tokens":
+		return &r.accessTokens
+	case "notifications":
+		return &r.notifications
+	case "integrations":
+		return &r.integrations
+	case "teams":
+		return &r.teams
+	case "users":
+		return &r.users
+	case "service_links":
+		return &r.serviceLinks
+	}
+	return nil
+}
+
+// all reports whether every type is exported.
+func (r *resources) all() bool {
+	return r == nil || r.projects && r.accessTokens && r.notifications && r.integrations && r.teams && r.users && r.serviceLinks
+}
+
+// selection returns the parts of the account to fetch to render the selected
+// types. Teams are listed whenever projects or users are exported, so that
+// the teams they belong to can be read through data sources. The users and
+// pending invitations of every team are only needed when teamUsers renders
+// memberships as rollbar_team_user resources, or to find the users of the
+// -fromTeams.
+func (r *resources) selection(f *filter, teamUsers bool) fetcher.Selection {
+	if r.all() {
+		sel := fetcher.SelectAll
+		sel.TeamInvites = teamUsers
+		return sel
+	}
+	fromTeams := !f.empty() && len(f.fromTeams) > 0
+	return fetcher.Selection{
+		Projects:      r.projects || r.accessTokens || r.notifications || r.integrations,
+		AccessTokens:  r.accessTokens,
+		Notifications: r.notifications,
+		Integrations:  r.integrations,
+		Teams:         r.teams || r.projects || r.users,
+		TeamProjects:  r.projects || fromTeams && (r.accessTokens || r.notifications || r.integrations),
+		TeamUsers:     (fromTeams || teamUsers) && r.users,
+		TeamInvites:   teamUsers && r.users,
+		Users:         r.users,
+		ServiceLinks:  r.serviceLinks,
+	}
+}
+
+// apply drops the types that are not exported from account. Projects that
+// are not exported but whose access tokens, notification rules or integrations
+// are, are
+// returned separately as dataProjects: the writers read them through data
+// sources.
+func (r *resources) apply(account *fetcher.Account) (kept *fetcher.Account, dataProjects []fetcher.Project) {
+	if r.all() {
+		return account, nil
+	}
+
+	kept = &fetcher.Account{}
+	for _, project := range account.Projects {
+		if !r.accessTokens {
+			project.AccessTokens = nil
+		}
+		if !r.notifications {
+			project.Notifications = nil
+		}
+		if !r.integrations {
+			project.Integrations = nil
+		}
+		switch {
+		case r.projects:
+			kept.Projects = append(kept.Projects, project)
+		case len(project.AccessTokens) > 0 || len(project.Notifications) > 0 || len(project.Integrations) > 0:
+			dataProjects = append(dataProjects, project)
+		}
+	}
+	if r.teams {
+		kept.Teams = account.Teams
+	}
+	if r.users {
+		kept.Users = account.Users
+	}
+	if r.serviceLinks {
+		kept.ServiceLinks = account.ServiceLinks
+	}
+	return kept, dataProjects
+}
+
+// writes reports whether the per-type file name is written for the selected
+// types.
+func (r *resources) writes(name string) bool {
+	for _, file := range r.files() {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+// files returns the per-type files to write for the selected types.
+func (r *resources) files() []string {
+	if r.all() {
+		return []string{"teams.tf", "projects.tf", "access_tokens.tf", "notifications.tf", "integrations.tf", "users.tf", "service_links.tf"}
+	}
+	var names []string
+	// Teams are also written when only projects or users are exported, as
+	// data sources.
+	if r.teams || r.projects || r.users {
+		names = append(names, "teams.tf")
+	}
+	if r.projects || r.accessTokens {
+		names = append(names, "projects.tf")
+	}
+	if r.accessTokens {
+		names = append(names, "access_tokens.tf")
+	}
+	if r.notifications {
+		names = append(names, "notifications.tf")
+	}
+	if r.integrations {
+		names = append(names, "integrations.tf")
+	}
+	if r.users {
+		names = append(names, "users.tf")
+	}
+	if r.serviceLinks {
+		names = append(names, "service_links.tf")
+	}
+	return names
+}
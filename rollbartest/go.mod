@@ -0,0 +1,7 @@
+module github.com/rollbar/rollbar-terraform-importer/rollbartest
+
+go 1.16
+
+require github.com/rollbar/rollbar-terraform-importer/fetcher v0.0.0
+
+replace github.com/rollbar/rollbar-terraform-importer/fetcher => ../fetcher
@@ -0,0 +1,459 @@
+// Package rollbartest provides a fake of the Rollbar API endpoints used by the
+// fetcher package, for tests and offline runs of the importer.
+//
+// The fake is seeded with a Fixture, either built from fetcher structs or
+// loaded from a JSON file, and can be told to fail, rate limit or paginate its
+// responses.
+package rollbartest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+// Fixture is the account served by a Server.
+//
+// Team membership is taken from Team.Users and Team.Projects, and the teams
+// of each user are derived from it, so User.Teams does not need to be set.
+// Team.Invites are served as they are, whatever their status.
+// Notification rules and integrations are served to requests made with a
+// read token of their project.
+type Fixture struct {
+	Projects     []fetcher.Project
+	Teams        []fetcher.Team
+	Users        []fetcher.User
+	ServiceLinks []fetcher.ServiceLink
+}
+
+// LoadFixture reads a Fixture from a JSON file.
+func LoadFixture(filename string) (Fixture, error) {
+	var fixture Fixture
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fixture, err
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return fixture, fmt.Errorf("parsing fixture %s: %w", filename, err)
+	}
+	return fixture, nil
+}
+
+// Server is a fake Rollbar API listening on a local port.
+type Server struct {
+	// AccessToken, when set, is the only account access token the server
+	// accepts. Requests with any other token get a 401.
+	AccessToken string
+
+	// PageSize, when set, is the most items the server returns per page of a
+	// paginated listing (users, team users and team invites), whatever the
+	// per_page query parameter asks for. Otherwise per_page is honoured as
+	// is, and without it every listing is returned on its first page. The
+	// other listings are never paginated.
+	PageSize int
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	fixture  Fixture
+	faults   []*fault
+	requests []string
+	tokens   []string
+}
+
+// fault is a canned response served instead of the real one for the next
+// remaining requests whose endpoint matches pattern.
+type fault struct {
+	pattern   string
+	remaining int
+	respond   func(w http.ResponseWriter)
+}
+
+// NewServer starts a Server serving the given fixture. Callers must Close it
+// when done.
+func NewServer(fixture Fixture) *Server {
+	s := &Server{fixture: fixture}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the base URL to hand to fetcher.WithBaseURL.
+func (s *Server) URL() string {
+	return s.server.URL + "/api/1/"
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Requests returns the endpoints requested so far, including their query
+// string, in the order they were received.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// AccessTokens returns the access tokens sent so far with requests to
+// endpoints matching pattern, in the order they were received. Patterns are
+// matched as they are by FailNext.
+func (s *Server) AccessTokens(pattern string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []string
+	for i, request := range s.requests {
+		endpoint := strings.SplitN(request, "?", 2)[0]
+		if ok, _ := path.Match(pattern, endpoint); ok {
+			tokens = append(tokens, s.tokens[i])
+		}
+	}
+	return tokens
+}
+
+// FailNext makes the next times requests to endpoints matching pattern fail
+// with the given HTTP status and a Rollbar error envelope carrying message.
+// Passing http.StatusOK produces an API-level error on a successful response.
+//
+// Patterns use path.Match syntax against the endpoint without its query
+// string, e.g. "users" or "team/*/projects".
+func (s *Server) FailNext(pattern string, times int, status int, message string) {
+	s.addFault(pattern, times, func(w http.ResponseWriter) {
+		writeJSON(w, status, map[string]interface{}{"err": 1, "message": message})
+	})
+}
+
+// RateLimitNext makes the next times requests to endpoints matching pattern
+// answer with a 429 and rate limit headers saying the window is exhausted.
+func (s *Server) RateLimitNext(pattern string, times int) {
+	s.addFault(pattern, times, func(w http.ResponseWriter) {
+		w.Header().Set("X-Rate-Limit-Remaining", "0")
+		w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+		writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{"err": 1, "message": "Rate limit exceeded"})
+	})
+}
+
+func (s *Server) addFault(pattern string, times int, respond func(w http.ResponseWriter)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{pattern: pattern, remaining: times, respond: respond})
+}
+
+// takeFault returns the first fault matching endpoint that has responses
+// left, using one of them up.
+func (s *Server) takeFault(endpoint string) *fault {
+	for _, f := range s.faults {
+		if f.remaining <= 0 {
+			continue
+		}
+		if ok, _ := path.Match(f.pattern, endpoint); ok {
+			f.remaining--
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/api/1/")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, strings.TrimPrefix(r.URL.RequestURI(), "/api/1/"))
+	s.tokens = append(s.tokens, r.Header.Get("X-Rollbar-Access-Token"))
+	f := s.takeFault(endpoint)
+	s.mu.Unlock()
+
+	if f != nil {
+		f.respond(w)
+		return
+	}
+	page := s.page(r)
+	token := r.Header.Get("X-Rollbar-Access-Token")
+
+	// Notification rules and integrations belong to a project and are read
+	// with one of its access tokens rather than with the account token.
+	parts := strings.Split(endpoint, "/")
+	if parts[0] == "notifications" {
+		project, ok := s.projectByToken(token, "read")
+		switch {
+		case !ok:
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"err": 1, "message": "invalid access token"})
+		case len(parts) == 3 && parts[2] == "rules":
+			writeAll(w, s.notifications(project, parts[1]))
+		case len(parts) == 2:
+			s.writeIntegration(w, project, parts[1])
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"err": 1, "message": "Not found"})
+		}
+		return
+	}
+
+	if s.AccessToken != "" && token != s.AccessToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"err": 1, "message": "invalid access token"})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "projects":
+		writeAll(w, s.projects())
+	case len(parts) == 1 && parts[0] == "teams":
+		writeAll(w, s.teams())
+	case len(parts) == 1 && parts[0] == "service_links":
+		writeAll(w, s.serviceLinks())
+	case len(parts) == 1 && parts[0] == "users":
+		users := page.slice(s.users())
+		writeJSON(w, http.StatusOK, map[string]interface{}{"err": 0, "result": map[string]interface{}{"users": users}})
+	case len(parts) == 3 && parts[0] == "project" && parts[2] == "access_tokens":
+		s.withID(w, parts[1], func(id int) { writeAll(w, s.accessTokens(id)) })
+	case len(parts) == 3 && parts[0] == "team" && parts[2] == "projects":
+		s.withID(w, parts[1], func(id int) { writeAll(w, s.teamProjects(id)) })
+	case len(parts) == 3 && parts[0] == "team" && parts[2] == "users":
+		s.withID(w, parts[1], func(id int) { s.writeList(w, page, s.teamUsers(id)) })
+	case len(parts) == 3 && parts[0] == "team" && parts[2] == "invites":
+		s.withID(w, parts[1], func(id int) { s.writeList(w, page, s.teamInvites(id)) })
+	case len(parts) == 3 && parts[0] == "user" && parts[2] == "teams":
+		s.withID(w, parts[1], func(id int) {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"err": 0, "result": map[string]interface{}{"teams": s.userTeams(id)}})
+		})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"err": 1, "message": "Not found"})
+	}
+}
+
+// withID parses a numeric path segment and answers with a 404 if it is not
+// one.
+func (s *Server) withID(w http.ResponseWriter, segment string, fn func(id int)) {
+	id, err := strconv.Atoi(segment)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"err": 1, "message": "Not found"})
+		return
+	}
+	fn(id)
+}
+
+func (s *Server) writeList(w http.ResponseWriter, page page, items []interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"err": 0, "result": page.slice(items)})
+}
+
+// writeAll answers with a listing the API does not paginate, ignoring any
+// page query parameters.
+func writeAll(w http.ResponseWriter, items []interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"err": 0, "result": items})
+}
+
+// page is the 1-based page of a listing a request asks for, and its size.
+// A size of zero means the whole listing is on the first page.
+type page struct {
+	number int
+	size   int
+}
+
+// page reads the page and per_page query parameters of r, capping the size
+// at PageSize.
+func (s *Server) page(r *http.Request) page {
+	number, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if number < 1 {
+		number = 1
+	}
+	size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if s.PageSize > 0 && (size <= 0 || size > s.PageSize) {
+		size = s.PageSize
+	}
+	return page{number: number, size: size}
+}
+
+// slice returns the items on the page.
+func (p page) slice(items []interface{}) []interface{} {
+	if p.size <= 0 {
+		if p.number > 1 {
+			return []interface{}{}
+		}
+		return items
+	}
+	start := (p.number - 1) * p.size
+	if start >= len(items) {
+		return []interface{}{}
+	}
+	end := start + p.size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+/*
+ * WIRE FORMAT
+ *
+ * The fetcher structs carry fields the importer fills in itself (access
+ * tokens, members), so the fixture is converted to the shape the API really
+ * returns before it is served.
+ */
+
+func (s *Server) projects() []interface{} {
+	items := []interface{}{}
+	for _, p := range s.fixture.Projects {
+		items = append(items, map[string]interface{}{"id": p.ID, "account_id": p.AccountID, "name": p.Name})
+	}
+	return items
+}
+
+func (s *Server) teams() []interface{} {
+	items := []interface{}{}
+	for _, t := range s.fixture.Teams {
+		items = append(items, teamJSON(t))
+	}
+	return items
+}
+
+func (s *Server) users() []interface{} {
+	items := []interface{}{}
+	for _, u := range s.fixture.Users {
+		items = append(items, map[string]interface{}{"id": u.ID, "email": u.Email, "username": u.Username})
+	}
+	return items
+}
+
+func (s *Server) serviceLinks() []interface{} {
+	items := []interface{}{}
+	for _, link := range s.fixture.ServiceLinks {
+		items = append(items, link)
+	}
+	return items
+}
+
+func (s *Server) accessTokens(projectID int) []interface{} {
+	items := []interface{}{}
+	for _, p := range s.fixture.Projects {
+		if p.ID != projectID {
+			continue
+		}
+		for _, token := range p.AccessTokens {
+			token.ProjectID = p.ID
+			items = append(items, token)
+		}
+	}
+	return items
+}
+
+// projectByToken returns the project an access token with the given scope
+// belongs to.
+func (s *Server) projectByToken(token string, scope string) (fetcher.Project, bool) {
+	for _, p := range s.fixture.Projects {
+		for _, accessToken := range p.AccessTokens {
+			if accessToken.AccessToken != token {
+				continue
+			}
+			for _, tokenScope := range accessToken.Scopes {
+				if tokenScope == scope {
+					return p, true
+				}
+			}
+		}
+	}
+	return fetcher.Project{}, false
+}
+
+func (s *Server) notifications(project fetcher.Project, channel string) []interface{} {
+	items := []interface{}{}
+	for _, rule := range project.Notifications {
+		if rule.Channel != channel {
+			continue
+		}
+		items = append(items, map[string]interface{}{
+			"id":      rule.ID,
+			"trigger": rule.Trigger,
+			"filters": rule.Filters,
+			"config":  rule.Config,
+		})
+	}
+	return items
+}
+
+// writeIntegration answers with the settings of the project's integration
+// with channel, or a 404 if it has none.
+func (s *Server) writeIntegration(w http.ResponseWriter, project fetcher.Project, channel string) {
+	for _, integration := range project.Integrations {
+		if integration.Channel == channel {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"err": 0, "result": integration.Settings})
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]interface{}{"err": 1, "message": "Not found"})
+}
+
+func (s *Server) teamProjects(teamID int) []interface{} {
+	items := []interface{}{}
+	for _, t := range s.fixture.Teams {
+		if t.ID != teamID {
+			continue
+		}
+		for _, projectID := range t.Projects {
+			items = append(items, fetcher.TeamProjects{TeamID: t.ID, ProjectID: projectID})
+		}
+	}
+	return items
+}
+
+func (s *Server) teamUsers(teamID int) []interface{} {
+	items := []interface{}{}
+	for _, t := range s.fixture.Teams {
+		if t.ID != teamID {
+			continue
+		}
+		for _, userID := range t.Users {
+			items = append(items, fetcher.TeamUsers{TeamID: t.ID, UserID: userID})
+		}
+	}
+	return items
+}
+
+func (s *Server) teamInvites(teamID int) []interface{} {
+	items := []interface{}{}
+	for _, t := range s.fixture.Teams {
+		if t.ID != teamID {
+			continue
+		}
+		for _, invitation := range t.Invites {
+			invitation.TeamID = t.ID
+			items = append(items, invitation)
+		}
+	}
+	return items
+}
+
+func (s *Server) userTeams(userID int) []interface{} {
+	items := []interface{}{}
+	for _, t := range s.fixture.Teams {
+		for _, member := range t.Users {
+			if member == userID {
+				items = append(items, teamJSON(t))
+				break
+			}
+		}
+	}
+	return items
+}
+
+func teamJSON(t fetcher.Team) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           t.ID,
+		"account_id":   t.AccountID,
+		"access_level": t.AccessLevel,
+		"name":         t.Name,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
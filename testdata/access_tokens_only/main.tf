@@ -0,0 +1,12 @@
+terraform {
+  required_providers {
+    rollbar = {
+      source  = "rollbar/rollbar"
+      version = "1.0.6"
+    }
+  }
+}
+
+provider "rollbar" {
+}
+
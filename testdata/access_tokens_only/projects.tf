@@ -0,0 +1,8 @@
+data "rollbar_project" "web" {
+  name = "web"
+}
+
+data "rollbar_project" "api" {
+  name = "api"
+}
+
@@ -0,0 +1,20 @@
+resource "rollbar_project_access_token" "web_post_client_item" {
+  name                    = "post_client_item"
+  project_id              = rollbar_project.web.id
+  depends_on              = [rollbar_project.web]
+  scopes                  = ["post_client_item"]
+  status                  = "enabled"
+  rate_limit_window_size  = 60
+  rate_limit_window_count = 1000
+}
+
+resource "rollbar_project_access_token" "web_read" {
+  name                    = "read"
+  project_id              = rollbar_project.web.id
+  depends_on              = [rollbar_project.web]
+  scopes                  = ["read"]
+  status                  = "enabled"
+  rate_limit_window_size  = 0
+  rate_limit_window_count = 0
+}
+
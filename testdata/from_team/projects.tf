@@ -0,0 +1,6 @@
+resource "rollbar_project" "web" {
+  name       = "web"
+  team_ids   = [rollbar_team.Frontend.id, data.rollbar_team.Owners.id]
+  depends_on = [rollbar_team.Frontend, data.rollbar_team.Owners]
+}
+
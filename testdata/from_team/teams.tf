@@ -0,0 +1,13 @@
+resource "rollbar_team" "Frontend" {
+  name         = "Frontend"
+  access_level = "standard"
+}
+
+data "rollbar_team" "Owners" {
+  team_id = 1
+}
+
+data "rollbar_team" "Backend" {
+  team_id = 3
+}
+
@@ -0,0 +1,20 @@
+import {
+  to = rollbar_project_access_token.web_post_client_item
+  id = "10/4f1c0ffee4f1c0ffee4f1c0ffee4f1c0"
+}
+
+import {
+  to = rollbar_project_access_token.web_read
+  id = "10/d00dd00dd00dd00dd00dd00dd00dd00d"
+}
+
+import {
+  to = rollbar_project_access_token.api_post_server_item
+  id = "11/a11ce0a11ce0a11ce0a11ce0a11ce0a1"
+}
+
+import {
+  to = rollbar_project_access_token.api_read
+  id = "11/b0bb0bb0bb0bb0bb0bb0bb0bb0bb0bb0"
+}
+
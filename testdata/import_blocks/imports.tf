@@ -0,0 +1,75 @@
+import {
+  to = rollbar_project.web
+  id = "10"
+}
+
+import {
+  to = rollbar_project.api
+  id = "11"
+}
+
+import {
+  to       = rollbar_notification.web_email_new_item
+  id       = "email:501"
+  provider = rollbar.web
+}
+
+import {
+  to       = rollbar_notification.web_slack_occurrence_rate
+  id       = "slack:502"
+  provider = rollbar.web
+}
+
+import {
+  to       = rollbar_integration.web_slack
+  id       = "slack"
+  provider = rollbar.web
+}
+
+import {
+  to       = rollbar_integration.web_pagerduty
+  id       = "pagerduty"
+  provider = rollbar.web
+}
+
+import {
+  to       = rollbar_integration.web_webhook
+  id       = "webhook"
+  provider = rollbar.web
+}
+
+import {
+  to = rollbar_team.Frontend
+  id = "2"
+}
+
+import {
+  to = rollbar_team.Backend
+  id = "3"
+}
+
+import {
+  to = rollbar_user.alice
+  id = "100"
+}
+
+import {
+  to = rollbar_user.bob
+  id = "101"
+}
+
+import {
+  to = rollbar_user.carol
+  id = "102"
+}
+
+import {
+  to = rollbar_service_link.GitHub
+  id = "201"
+}
+
+import {
+  to = rollbar_service_link.Datadog
+  id = "202"
+}
+
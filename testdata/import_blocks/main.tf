@@ -0,0 +1,22 @@
+terraform {
+  required_providers {
+    rollbar = {
+      source  = "rollbar/rollbar"
+      version = "1.0.6"
+    }
+  }
+}
+
+provider "rollbar" {
+}
+
+provider "rollbar" {
+  alias           = "web"
+  project_api_key = var.rollbar_web_project_api_key
+}
+
+variable "rollbar_web_project_api_key" {
+  type      = string
+  sensitive = true
+}
+
@@ -0,0 +1,39 @@
+resource "rollbar_notification" "web_email_new_item" {
+  provider = rollbar.web
+  channel  = "email"
+  rule {
+    trigger = "new_item"
+    filters {
+      type      = "environment"
+      operation = "eq"
+      value     = "production"
+    }
+    filters {
+      type      = "level"
+      operation = "gte"
+      value     = "error"
+    }
+  }
+  config {
+    teams = ["Frontend"]
+    users = ["alice@example.com"]
+  }
+}
+
+resource "rollbar_notification" "web_slack_occurrence_rate" {
+  provider = rollbar.web
+  channel  = "slack"
+  rule {
+    trigger = "occurrence_rate"
+    filters {
+      type   = "rate"
+      period = 300
+      count  = 100
+    }
+  }
+  config {
+    channel              = "#web-alerts"
+    show_message_buttons = true
+  }
+}
+
@@ -0,0 +1,12 @@
+resource "rollbar_project" "web" {
+  name       = "web"
+  team_ids   = [rollbar_team.Frontend.id, data.rollbar_team.Owners.id]
+  depends_on = [rollbar_team.Frontend, data.rollbar_team.Owners]
+}
+
+resource "rollbar_project" "api" {
+  name       = "api"
+  team_ids   = [rollbar_team.Backend.id, data.rollbar_team.Owners.id]
+  depends_on = [rollbar_team.Backend, data.rollbar_team.Owners]
+}
+
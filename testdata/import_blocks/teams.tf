@@ -0,0 +1,14 @@
+data "rollbar_team" "Owners" {
+  team_id = 1
+}
+
+resource "rollbar_team" "Frontend" {
+  name         = "Frontend"
+  access_level = "standard"
+}
+
+resource "rollbar_team" "Backend" {
+  name         = "Backend"
+  access_level = "light"
+}
+
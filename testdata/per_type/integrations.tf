@@ -0,0 +1,36 @@
+resource "rollbar_integration" "web_slack" {
+  provider = rollbar.web
+  slack {
+    channel              = "#web-alerts"
+    enabled              = true
+    service_account_id   = "54321"
+    show_message_buttons = true
+  }
+}
+
+resource "rollbar_integration" "web_pagerduty" {
+  provider = rollbar.web
+  pagerduty {
+    enabled     = true
+    service_key = var.rollbar_web_pagerduty_service_key
+  }
+}
+
+variable "rollbar_web_pagerduty_service_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "rollbar_integration" "web_webhook" {
+  provider = rollbar.web
+  webhook {
+    enabled = false
+    url     = var.rollbar_web_webhook_url
+  }
+}
+
+variable "rollbar_web_webhook_url" {
+  type      = string
+  sensitive = true
+}
+
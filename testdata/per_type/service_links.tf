@@ -0,0 +1,10 @@
+resource "rollbar_service_link" "GitHub" {
+  name     = "GitHub"
+  template = "https://github.com/example/web/blob/{{ code_version }}/{{ filename }}#L{{ lineno }}"
+}
+
+resource "rollbar_service_link" "Datadog" {
+  name     = "Datadog"
+  template = "https://app.datadoghq.com/logs?query=host:{{ server.host }}"
+}
+
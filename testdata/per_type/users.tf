@@ -0,0 +1,14 @@
+resource "rollbar_user" "alice" {
+  email    = "alice@example.com"
+  team_ids = [rollbar_team.Frontend.id, data.rollbar_team.Owners.id]
+}
+
+resource "rollbar_user" "bob" {
+  email    = "bob@example.com"
+  team_ids = [rollbar_team.Backend.id, rollbar_team.Frontend.id]
+}
+
+resource "rollbar_user" "carol" {
+  email = "carol@example.com"
+}
+
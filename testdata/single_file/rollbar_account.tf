@@ -0,0 +1,187 @@
+terraform {
+  required_providers {
+    rollbar = {
+      source  = "rollbar/rollbar"
+      version = "1.0.6"
+    }
+  }
+}
+
+provider "rollbar" {
+}
+
+provider "rollbar" {
+  alias           = "web"
+  project_api_key = var.rollbar_web_project_api_key
+}
+
+variable "rollbar_web_project_api_key" {
+  type      = string
+  sensitive = true
+}
+
+data "rollbar_team" "Owners" {
+  team_id = 1
+}
+
+resource "rollbar_team" "Frontend" {
+  name         = "Frontend"
+  access_level = "standard"
+}
+
+resource "rollbar_team" "Backend" {
+  name         = "Backend"
+  access_level = "light"
+}
+
+resource "rollbar_project" "web" {
+  name       = "web"
+  team_ids   = [rollbar_team.Frontend.id, data.rollbar_team.Owners.id]
+  depends_on = [rollbar_team.Frontend, data.rollbar_team.Owners]
+}
+
+resource "rollbar_project" "api" {
+  name       = "api"
+  team_ids   = [rollbar_team.Backend.id, data.rollbar_team.Owners.id]
+  depends_on = [rollbar_team.Backend, data.rollbar_team.Owners]
+}
+
+resource "rollbar_project_access_token" "web_post_client_item" {
+  name                    = "post_client_item"
+  project_id              = rollbar_project.web.id
+  depends_on              = [rollbar_project.web]
+  scopes                  = ["post_client_item"]
+  status                  = "enabled"
+  rate_limit_window_size  = 60
+  rate_limit_window_count = 1000
+}
+
+resource "rollbar_project_access_token" "web_read" {
+  name                    = "read"
+  project_id              = rollbar_project.web.id
+  depends_on              = [rollbar_project.web]
+  scopes                  = ["read"]
+  status                  = "enabled"
+  rate_limit_window_size  = 0
+  rate_limit_window_count = 0
+}
+
+resource "rollbar_project_access_token" "api_post_server_item" {
+  name                    = "post_server_item"
+  project_id              = rollbar_project.api.id
+  depends_on              = [rollbar_project.api]
+  scopes                  = ["post_server_item"]
+  status                  = "enabled"
+  rate_limit_window_size  = 0
+  rate_limit_window_count = 0
+}
+
+resource "rollbar_project_access_token" "api_read" {
+  name                    = "read"
+  project_id              = rollbar_project.api.id
+  depends_on              = [rollbar_project.api]
+  scopes                  = ["read"]
+  status                  = "disabled"
+  rate_limit_window_size  = 0
+  rate_limit_window_count = 0
+}
+
+resource "rollbar_notification" "web_email_new_item" {
+  provider = rollbar.web
+  channel  = "email"
+  rule {
+    trigger = "new_item"
+    filters {
+      type      = "environment"
+      operation = "eq"
+      value     = "production"
+    }
+    filters {
+      type      = "level"
+      operation = "gte"
+      value     = "error"
+    }
+  }
+  config {
+    teams = ["Frontend"]
+    users = ["alice@example.com"]
+  }
+}
+
+resource "rollbar_notification" "web_slack_occurrence_rate" {
+  provider = rollbar.web
+  channel  = "slack"
+  rule {
+    trigger = "occurrence_rate"
+    filters {
+      type   = "rate"
+      period = 300
+      count  = 100
+    }
+  }
+  config {
+    channel              = "#web-alerts"
+    show_message_buttons = true
+  }
+}
+
+resource "rollbar_integration" "web_slack" {
+  provider = rollbar.web
+  slack {
+    channel              = "#web-alerts"
+    enabled              = true
+    service_account_id   = "54321"
+    show_message_buttons = true
+  }
+}
+
+resource "rollbar_integration" "web_pagerduty" {
+  provider = rollbar.web
+  pagerduty {
+    enabled     = true
+    service_key = var.rollbar_web_pagerduty_service_key
+  }
+}
+
+variable "rollbar_web_pagerduty_service_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "rollbar_integration" "web_webhook" {
+  provider = rollbar.web
+  webhook {
+    enabled = false
+    url     = var.rollbar_web_webhook_url
+  }
+}
+
+variable "rollbar_web_webhook_url" {
+  type      = string
+  sensitive = true
+}
+
+resource "rollbar_user" "alice" {
+  email    = "alice@example.com"
+  team_ids = [rollbar_team.Frontend.id, data.rollbar_team.Owners.id]
+}
+
+resource "rollbar_user" "bob" {
+  email    = "bob@example.com"
+  team_ids = [rollbar_team.Backend.id, rollbar_team.Frontend.id]
+}
+
+resource "rollbar_user" "carol" {
+  email = "carol@example.com"
+}
+
+resource "rollbar_service_link" "GitHub" {
+  name     = "GitHub"
+  template = "https://github.com/example/web/blob/{{ code_version }}/{{ filename }}#L{{ lineno }}"
+}
+
+resource "rollbar_service_link" "Datadog" {
+  name     = "Datadog"
+  template = "https://app.datadoghq.com/logs?query=host:{{ server.host }}"
+}
+
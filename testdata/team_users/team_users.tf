@@ -0,0 +1,25 @@
+resource "rollbar_team_user" "Owners_alice" {
+  team_id = data.rollbar_team.Owners.id
+  email   = "alice@example.com"
+}
+
+resource "rollbar_team_user" "Frontend_alice" {
+  team_id = rollbar_team.Frontend.id
+  email   = "alice@example.com"
+}
+
+resource "rollbar_team_user" "Frontend_bob" {
+  team_id = rollbar_team.Frontend.id
+  email   = "bob@example.com"
+}
+
+resource "rollbar_team_user" "Frontend_dave_example_com" {
+  team_id = rollbar_team.Frontend.id
+  email   = "dave@example.com"
+}
+
+resource "rollbar_team_user" "Backend_bob" {
+  team_id = rollbar_team.Backend.id
+  email   = "bob@example.com"
+}
+
@@ -0,0 +1,12 @@
+resource "rollbar_user" "alice" {
+  email = "alice@example.com"
+}
+
+resource "rollbar_user" "bob" {
+  email = "bob@example.com"
+}
+
+resource "rollbar_user" "carol" {
+  email = "carol@example.com"
+}
+
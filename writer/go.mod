@@ -2,6 +2,10 @@ module github.com/rollbar/rollbar-terraform-importer/writer
 
 go 1.16
 
-require github.com/rollbar/rollbar-terraform-importer/fetcher v0.0.0
+require (
+	github.com/hashicorp/hcl/v2 v2.8.2
+	github.com/rollbar/rollbar-terraform-importer/fetcher v0.0.0
+	github.com/zclconf/go-cty v1.8.4
+)
 
 replace github.com/rollbar/rollbar-terraform-importer/fetcher => ../fetcher
@@ -0,0 +1,104 @@
+package writer
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// appendResource adds a `resource "<resourceType>" "<name>" {}` block to the
+// given body, followed by a blank line, and returns the block's body so the
+// caller can set its attributes.
+func appendResource(body *hclwrite.Body, resourceType string, name string) *hclwrite.Body {
+	block := body.AppendNewBlock("resource", []string{resourceType, name})
+	body.AppendNewline()
+	return block.Body()
+}
+
+// reference builds a traversal such as `rollbar_team.owners.id` from its
+// dot-separated parts.
+func reference(root string, attrs ...string) hcl.Traversal {
+	traversal := hcl.Traversal{hcl.TraverseRoot{Name: root}}
+	for _, attr := range attrs {
+		traversal = append(traversal, hcl.TraverseAttr{Name: attr})
+	}
+	return traversal
+}
+
+// referenceList renders a list expression whose elements are references,
+// e.g. `[rollbar_team.a.id, rollbar_team.b.id]`.
+func referenceList(traversals []hcl.Traversal) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")},
+	}
+	for i, traversal := range traversals {
+		if i > 0 {
+			tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+		}
+		tokens = append(tokens, hclwrite.TokensForTraversal(traversal)...)
+	}
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")})
+	return tokens
+}
+
+// stringList converts a []string into a cty list value, keeping an empty list
+// rather than null when there are no elements.
+func stringList(values []string) cty.Value {
+	if len(values) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+	elements := make([]cty.Value, len(values))
+	for i, value := range values {
+		elements[i] = cty.StringVal(value)
+	}
+	return cty.ListVal(elements)
+}
+
+// setAttributes sets an attribute on body for every value decoded from the
+// API's JSON, in key order. Strings, numbers, booleans and lists of those are
+// supported; anything else is skipped.
+func setAttributes(body *hclwrite.Body, values map[string]interface{}) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if value, ok := jsonValue(values[key]); ok {
+			body.SetAttributeValue(key, value)
+		}
+	}
+}
+
+// jsonValue converts a value decoded from JSON into a cty value.
+func jsonValue(value interface{}) (cty.Value, bool) {
+	switch value := value.(type) {
+	case string:
+		return cty.StringVal(value), true
+	case bool:
+		return cty.BoolVal(value), true
+	case float64:
+		return cty.NumberFloatVal(value), true
+	case []interface{}:
+		elements := make([]cty.Value, 0, len(value))
+		for _, element := range value {
+			converted, ok := jsonValue(element)
+			if !ok {
+				return cty.NilVal, false
+			}
+			elements = append(elements, converted)
+		}
+		return cty.TupleVal(elements), true
+	}
+	return cty.NilVal, false
+}
+
+// writeHCL formats the given file the way `terraform fmt` would and writes it
+// to a user-defined file.
+func writeHCL(file *hclwrite.File, filename string) error {
+	return writeFile(filename, hclwrite.Format(file.Bytes()))
+}
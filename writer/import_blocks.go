@@ -0,0 +1,144 @@
+package writer
+
+import (
+	"strconv"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+	"github.com/zclconf/go-cty/cty"
+)
+
+/*
+ * IMPORT BLOCKS
+ *
+ * Terraform 1.5 and later accept `import {}` blocks in the configuration, so a
+ * single `terraform plan` imports and validates the whole account instead of
+ * running one `terraform import` per resource. These mirror the
+ * Write*ImportCommands functions and use the same resource names.
+ */
+
+// WriteProjectAccessTokenImportBlocks writes an import block for every access
+// token of every project to a user-defined file.
+func WriteProjectAccessTokenImportBlocks(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, project := range projects {
+		for _, accessToken := range project.AccessTokens {
+			appendImport(file.Body(),
+				reference("rollbar_project_access_token", names.AccessToken(project, accessToken)),
+				strconv.Itoa(project.ID)+"/"+accessToken.AccessToken)
+		}
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteProjectImportBlocks writes an import block for every project but data
+// source projects to a user-defined file.
+func WriteProjectImportBlocks(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, project := range projects {
+		if names.dataProject(project) {
+			continue
+		}
+		appendImport(file.Body(),
+			reference("rollbar_project", names.Project(project)),
+			strconv.Itoa(project.ID))
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteTeamImportBlocks writes an import block for every team but the Owners
+// team and other data source teams to a user-defined file.
+func WriteTeamImportBlocks(names *Names, teams []fetcher.Team, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, team := range teams {
+		if names.dataTeam(team) {
+			continue
+		}
+		appendImport(file.Body(),
+			reference("rollbar_team", names.Team(team)),
+			strconv.Itoa(team.ID))
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteUserImportBlocks writes an import block for every user to a
+// user-defined file.
+func WriteUserImportBlocks(names *Names, users []fetcher.User, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, user := range users {
+		appendImport(file.Body(),
+			reference("rollbar_user", names.User(user)),
+			strconv.Itoa(user.ID))
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteTeamUserImportBlocks writes an import block for every member of every
+// team to a user-defined file.
+func WriteTeamUserImportBlocks(names *Names, teams []fetcher.Team, users []fetcher.User, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	byID := usersByID(users)
+	for _, team := range teams {
+		for _, member := range teamMembers(team, byID) {
+			appendImport(file.Body(),
+				reference("rollbar_team_user", names.TeamUser(team, member.email)),
+				teamUserImportID(team, member.email))
+		}
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteServiceLinkImportBlocks writes an import block for every service link
+// to a user-defined file.
+func WriteServiceLinkImportBlocks(names *Names, serviceLinks []fetcher.ServiceLink, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, link := range serviceLinks {
+		appendImport(file.Body(),
+			reference("rollbar_service_link", names.ServiceLink(link)),
+			strconv.Itoa(link.ID))
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteNotificationImportBlocks writes an import block for every
+// notification rule of every project to a user-defined file, each importing
+// through the provider configuration of its project.
+func WriteNotificationImportBlocks(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, project := range projects {
+		for _, rule := range project.Notifications {
+			block := appendImport(file.Body(),
+				reference("rollbar_notification", names.Notification(project, rule)),
+				notificationImportID(rule))
+			block.SetAttributeTraversal("provider", reference("rollbar", names.Project(project)))
+		}
+	}
+	return writeHCL(file, filename)
+}
+
+// WriteIntegrationImportBlocks writes an import block for every integration
+// of every project to a user-defined file, each importing through the
+// provider configuration of its project.
+func WriteIntegrationImportBlocks(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	for _, project := range projects {
+		for _, integration := range project.Integrations {
+			block := appendImport(file.Body(),
+				reference("rollbar_integration", names.Integration(project, integration)),
+				integration.Channel)
+			block.SetAttributeTraversal("provider", reference("rollbar", names.Project(project)))
+		}
+	}
+	return writeHCL(file, filename)
+}
+
+// appendImport adds an `import { to = <to>, id = "<id>" }` block to the given
+// body, followed by a blank line, and returns the block's body.
+func appendImport(body *hclwrite.Body, to hcl.Traversal, id string) *hclwrite.Body {
+	block := body.AppendNewBlock("import", nil)
+	block.Body().SetAttributeTraversal("to", to)
+	block.Body().SetAttributeValue("id", cty.StringVal(id))
+	body.AppendNewline()
+	return block.Body()
+}
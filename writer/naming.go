@@ -0,0 +1,369 @@
+package writer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+// Names hands out the Terraform resource names for every resource the
+// importer writes.
+//
+// Names are assigned per resource type from the full list of resources, so
+// the resource writers and the import command writers always agree on them.
+// Every name is a valid HCL identifier, and resources whose names would
+// collide are disambiguated deterministically by suffixing their Rollbar ID.
+type Names struct {
+	projects     *registry
+	teams        *registry
+	users        *registry
+	accessTokens *registry
+	rules        *registry
+	integrations *registry
+	serviceLinks *registry
+	teamUsers    *registry
+
+	dataProjects      map[int]bool
+	dataTeams         map[int]bool
+	teamUserResources bool
+}
+
+// Overrides are resource names picked by the user, keyed by Rollbar ID. They
+// take precedence over the names derived from the Rollbar names, and other
+// resources are named around them.
+type Overrides struct {
+	Projects map[int]string
+	Teams    map[int]string
+	Users    map[int]string
+}
+
+// NewNames assigns names to all the given projects, their access tokens,
+// teams, users, team memberships and service links, honouring the given
+// overrides.
+func NewNames(projects []fetcher.Project, teams []fetcher.Team, users []fetcher.User, serviceLinks []fetcher.ServiceLink, overrides Overrides) *Names {
+	var projectEntries, tokenEntries, teamEntries, userEntries []entry
+
+	for _, project := range projects {
+		projectEntries = append(projectEntries, entry{
+			key:      strconv.Itoa(project.ID),
+			label:    project.Name,
+			suffix:   strconv.Itoa(project.ID),
+			override: overrides.Projects[project.ID],
+		})
+	}
+	names := &Names{projects: newRegistry("project", projectEntries)}
+
+	for _, project := range projects {
+		projectName := names.Project(project)
+		for _, accessToken := range project.AccessTokens {
+			tokenEntries = append(tokenEntries, entry{
+				key:    accessTokenKey(project.ID, accessToken),
+				label:  projectName + "_" + accessToken.Name,
+				suffix: shortHash(accessToken.AccessToken),
+			})
+		}
+	}
+	names.accessTokens = newRegistry("access_token", tokenEntries)
+
+	var ruleEntries []entry
+	for _, project := range projects {
+		projectName := names.Project(project)
+		for _, rule := range project.Notifications {
+			ruleEntries = append(ruleEntries, entry{
+				key:    notificationKey(project.ID, rule),
+				label:  projectName + "_" + rule.Channel + "_" + rule.Trigger,
+				suffix: strconv.Itoa(rule.ID),
+			})
+		}
+	}
+	names.rules = newRegistry("notification", ruleEntries)
+
+	var integrationEntries []entry
+	for _, project := range projects {
+		for _, integration := range project.Integrations {
+			integrationEntries = append(integrationEntries, entry{
+				key:    integrationKey(project.ID, integration),
+				label:  names.Project(project) + "_" + integration.Channel,
+				suffix: strconv.Itoa(project.ID),
+			})
+		}
+	}
+	names.integrations = newRegistry("integration", integrationEntries)
+
+	for _, team := range teams {
+		teamEntries = append(teamEntries, entry{
+			key:      strconv.Itoa(team.ID),
+			label:    team.Name,
+			suffix:   strconv.Itoa(team.ID),
+			override: overrides.Teams[team.ID],
+		})
+	}
+	names.teams = newRegistry("team", teamEntries)
+
+	for _, user := range users {
+		userEntries = append(userEntries, entry{
+			key:      strconv.Itoa(user.ID),
+			label:    userLabel(user),
+			suffix:   strconv.Itoa(user.ID),
+			override: overrides.Users[user.ID],
+		})
+	}
+	names.users = newRegistry("user", userEntries)
+
+	var teamUserEntries []entry
+	byID := usersByID(users)
+	for _, team := range teams {
+		teamName := names.Team(team)
+		for _, member := range teamMembers(team, byID) {
+			teamUserEntries = append(teamUserEntries, entry{
+				key:    teamUserKey(team.ID, member.email),
+				label:  teamName + "_" + member.label,
+				suffix: strconv.Itoa(team.ID),
+			})
+		}
+	}
+	names.teamUsers = newRegistry("team_user", teamUserEntries)
+
+	var serviceLinkEntries []entry
+	for _, link := range serviceLinks {
+		serviceLinkEntries = append(serviceLinkEntries, entry{
+			key:    strconv.Itoa(link.ID),
+			label:  link.Name,
+			suffix: strconv.Itoa(link.ID),
+		})
+	}
+	names.serviceLinks = newRegistry("service_link", serviceLinkEntries)
+
+	return names
+}
+
+// Project returns the resource name of a rollbar_project.
+func (n *Names) Project(project fetcher.Project) string {
+	return n.projects.name(strconv.Itoa(project.ID), project.Name, strconv.Itoa(project.ID))
+}
+
+// Team returns the resource name of a rollbar_team.
+func (n *Names) Team(team fetcher.Team) string {
+	return n.teams.name(strconv.Itoa(team.ID), team.Name, strconv.Itoa(team.ID))
+}
+
+// User returns the resource name of a rollbar_user.
+func (n *Names) User(user fetcher.User) string {
+	return n.users.name(strconv.Itoa(user.ID), userLabel(user), strconv.Itoa(user.ID))
+}
+
+// ServiceLink returns the resource name of a rollbar_service_link.
+func (n *Names) ServiceLink(link fetcher.ServiceLink) string {
+	return n.serviceLinks.name(strconv.Itoa(link.ID), link.Name, strconv.Itoa(link.ID))
+}
+
+// TeamUser returns the resource name of the rollbar_team_user that adds email
+// to team.
+func (n *Names) TeamUser(team fetcher.Team, email string) string {
+	return n.teamUsers.name(teamUserKey(team.ID, email), n.Team(team)+"_"+email, strconv.Itoa(team.ID))
+}
+
+// UseTeamUserResources makes the writers express team membership through
+// rollbar_team_user resources, written by WriteTeamUsers, instead of the
+// team_ids of every rollbar_user.
+func (n *Names) UseTeamUserResources() {
+	n.teamUserResources = true
+}
+
+// UseTeamDataSource makes the writers read team through a rollbar_team data
+// source instead of managing it as a resource, e.g. for a team that is left
+// out of a filtered export but that exported projects still belong to.
+func (n *Names) UseTeamDataSource(team fetcher.Team) {
+	if n.dataTeams == nil {
+		n.dataTeams = map[int]bool{}
+	}
+	n.dataTeams[team.ID] = true
+}
+
+// Notification returns the resource name of a rollbar_notification.
+func (n *Names) Notification(project fetcher.Project, rule fetcher.NotificationRule) string {
+	return n.rules.name(notificationKey(project.ID, rule),
+		n.Project(project)+"_"+rule.Channel+"_"+rule.Trigger, strconv.Itoa(rule.ID))
+}
+
+// Integration returns the resource name of a rollbar_integration.
+func (n *Names) Integration(project fetcher.Project, integration fetcher.Integration) string {
+	return n.integrations.name(integrationKey(project.ID, integration),
+		n.Project(project)+"_"+integration.Channel, strconv.Itoa(project.ID))
+}
+
+// UseProjectDataSource makes the writers read project through a
+// rollbar_project data source instead of managing it as a resource, e.g. when
+// only the project's access tokens are exported.
+func (n *Names) UseProjectDataSource(project fetcher.Project) {
+	if n.dataProjects == nil {
+		n.dataProjects = map[int]bool{}
+	}
+	n.dataProjects[project.ID] = true
+}
+
+// dataProject reports whether project is read through a rollbar_project data
+// source rather than managed as a resource.
+func (n *Names) dataProject(project fetcher.Project) bool {
+	return n.dataProjects[project.ID]
+}
+
+// dataTeam reports whether team is read through a rollbar_team data source
+// rather than managed as a resource: the built-in Owners team, and teams
+// passed to UseTeamDataSource.
+func (n *Names) dataTeam(team fetcher.Team) bool {
+	return isOwnersTeam(team) || n.dataTeams[team.ID]
+}
+
+// AccessToken returns the resource name of a rollbar_project_access_token.
+func (n *Names) AccessToken(project fetcher.Project, accessToken fetcher.AccessToken) string {
+	return n.accessTokens.name(accessTokenKey(project.ID, accessToken),
+		n.Project(project)+"_"+accessToken.Name, shortHash(accessToken.AccessToken))
+}
+
+// entry is a single resource to be named: key identifies it uniquely within
+// its type, label is the human-readable name it is derived from and suffix is
+// what gets appended when the label alone would collide. A non-empty override
+// is used as the name instead of the label.
+type entry struct {
+	key      string
+	label    string
+	suffix   string
+	override string
+}
+
+// registry holds the names assigned to every resource of one type.
+type registry struct {
+	kind  string
+	names map[string]string
+}
+
+// newRegistry names every entry after its sanitized label. When several
+// entries share a label, all of them get their suffix appended, so the name a
+// resource receives does not depend on the order the API returned it in.
+// Overridden entries are named first, so that they keep the name they were
+// given.
+func newRegistry(kind string, entries []entry) *registry {
+	r := &registry{kind: kind, names: map[string]string{}}
+
+	sorted := make([]entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if (sorted[i].override != "") != (sorted[j].override != "") {
+			return sorted[i].override != ""
+		}
+		return sorted[i].key < sorted[j].key
+	})
+
+	uses := map[string]int{}
+	for _, e := range sorted {
+		if e.override == "" {
+			uses[identifier(e.label, kind)]++
+		}
+	}
+
+	taken := map[string]bool{}
+	for _, e := range sorted {
+		if _, ok := r.names[e.key]; ok {
+			continue
+		}
+		name := identifier(e.label, kind)
+		suffixed := false
+		if e.override != "" {
+			name = identifier(e.override, kind)
+		} else if uses[name] > 1 {
+			name = name + "_" + e.suffix
+			suffixed = true
+		}
+		// A name may still clash with an override or with another resource's
+		// label, e.g. "app" with ID 1 next to a resource literally named
+		// "app_1". The suffix is tried first, and a counter only when the
+		// suffixed name is taken as well.
+		if taken[name] && !suffixed {
+			name = name + "_" + e.suffix
+		}
+		for candidate, i := name, 2; ; i++ {
+			if !taken[candidate] {
+				name = candidate
+				break
+			}
+			candidate = name + "_" + strconv.Itoa(i)
+		}
+		taken[name] = true
+		r.names[e.key] = name
+	}
+	return r
+}
+
+// name returns the name registered for key. Resources that were not known
+// when the registry was built, e.g. a team only referenced from a user, fall
+// back to their label and suffix.
+func (r *registry) name(key string, label string, suffix string) string {
+	if name, ok := r.names[key]; ok {
+		return name
+	}
+	return identifier(label, r.kind) + "_" + suffix
+}
+
+var (
+	invalidIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	repeatedUnderscores    = regexp.MustCompile(`_{2,}`)
+)
+
+// identifier turns an arbitrary string into a valid Terraform identifier:
+// anything that is not an ASCII letter, digit, underscore or hyphen becomes an
+// underscore, and identifiers that would not start with a letter or an
+// underscore are prefixed with the resource kind.
+func identifier(label string, kind string) string {
+	name := invalidIdentifierChars.ReplaceAllString(label, "_")
+	name = repeatedUnderscores.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+
+	if name == "" {
+		return kind
+	}
+	if c := name[0]; !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+		if name = strings.TrimLeft(name, "-"); name == "" {
+			return kind
+		}
+		return kind + "_" + name
+	}
+	return name
+}
+
+// userLabel picks what a user's resource is named after: the username, or the
+// email address for users that have not picked a username yet.
+func userLabel(user fetcher.User) string {
+	if user.Username != "" {
+		return user.Username
+	}
+	return user.Email
+}
+
+func accessTokenKey(projectID int, accessToken fetcher.AccessToken) string {
+	return strconv.Itoa(projectID) + "/" + accessToken.AccessToken
+}
+
+func notificationKey(projectID int, rule fetcher.NotificationRule) string {
+	return strconv.Itoa(projectID) + "/" + rule.Channel + "/" + strconv.Itoa(rule.ID)
+}
+
+func teamUserKey(teamID int, email string) string {
+	return strconv.Itoa(teamID) + "/" + email
+}
+
+func integrationKey(projectID int, integration fetcher.Integration) string {
+	return strconv.Itoa(projectID) + "/" + integration.Channel
+}
+
+// shortHash identifies an access token in its resource name without putting
+// the secret itself into the configuration.
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:8]
+}
@@ -0,0 +1,94 @@
+package writer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Output is the set of files a run of the importer generates in a directory.
+//
+// The Write functions append to the files they are given, so several of them
+// can render into the same file. To keep that from piling onto the files of an
+// earlier run, the files are written to a staging directory next to their
+// destination and only moved into place by Commit, once all of them are
+// complete. A crash or an aborted run therefore never leaves half a file
+// behind.
+type Output struct {
+	dir     string
+	staging string
+	names   []string
+	stale   []string
+}
+
+// ExistsError is returned by NewOutput when some of the files to generate
+// already exist and may not be replaced.
+type ExistsError struct {
+	Paths []string
+}
+
+func (e *ExistsError) Error() string {
+	return "refusing to overwrite existing files: " + strings.Join(e.Paths, ", ")
+}
+
+// NewOutput prepares the generation of the named files in dir. The stale
+// files are ones an earlier run may have generated with other options, which
+// would declare the same resources a second time if left in place. Unless
+// force is set, it fails with an *ExistsError when any of the named or stale
+// files already exists.
+func NewOutput(dir string, names, stale []string, force bool) (*Output, error) {
+	if !force {
+		var existing []string
+		for _, name := range append(append([]string(nil), names...), stale...) {
+			path := filepath.Join(dir, name)
+			if _, err := os.Lstat(path); err == nil {
+				existing = append(existing, path)
+			}
+		}
+		if len(existing) > 0 {
+			sort.Strings(existing)
+			return nil, &ExistsError{Paths: existing}
+		}
+	}
+
+	staging, err := ioutil.TempDir(dir, ".rollbar-terraform-importer-")
+	if err != nil {
+		return nil, err
+	}
+	return &Output{dir: dir, staging: staging, names: names, stale: stale}, nil
+}
+
+// Path returns the staging path the named file is to be written to.
+func (o *Output) Path(name string) string {
+	return filepath.Join(o.staging, name)
+}
+
+// Commit moves every file written so far into place, replacing any file of
+// the same name, removes the stale files and removes the staging directory.
+func (o *Output) Commit() error {
+	defer o.Discard()
+
+	for _, name := range o.names {
+		staged := o.Path(name)
+		if _, err := os.Stat(staged); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(staged, filepath.Join(o.dir, name)); err != nil {
+			return err
+		}
+	}
+	for _, name := range o.stale {
+		if err := os.Remove(filepath.Join(o.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discard removes the staging directory and everything written to it. It is
+// a no-op after Commit.
+func (o *Output) Discard() {
+	os.RemoveAll(o.staging)
+}
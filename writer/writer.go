@@ -1,238 +1,674 @@
 package writer
 
 import (
-	"log"
 	"os"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+	"github.com/zclconf/go-cty/cty"
 )
 
+// DefaultProviderVersion is the Rollbar provider version constraint written
+// when none is configured.
+const DefaultProviderVersion = "1.0.6"
+
+// Backends are the Terraform backends a Provider may configure.
+var Backends = []string{"s3", "gcs", "local", "remote"}
+
+// Provider configures the boilerplate written by WriteProviderBlocks.
+type Provider struct {
+	// Version is the version constraint of the Rollbar provider.
+	Version string
+
+	// TerraformVersion, when set, is the required_version constraint of
+	// Terraform itself.
+	TerraformVersion string
+
+	// Variables wires the provider's api_key and project_api_key to the
+	// rollbar_api_key and rollbar_project_api_key variables, which are
+	// declared alongside it.
+	Variables bool
+
+	// Backend, when set, is the type of the backend block to write, one of
+	// Backends, and BackendConfig its attributes. Keys of the form
+	// block.attribute set an attribute of a nested block, e.g. the
+	// workspaces.name of the remote backend.
+	Backend       string
+	BackendConfig map[string]string
+}
+
 // WriteProviderBlocks writes, to a user-defined file, the boilerplate
 // necessary for Terraform to pull the Rollbar provider and make the output
 // a functioning Terraform project.
-func WriteProviderBlocks(filename string) {
-	outputFile := writeFile(filename)
+func WriteProviderBlocks(provider Provider, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
 
-	resource := `terraform {
-  required_providers {
-    rollbar = {
-      source = "rollbar/rollbar"
-      version = "1.0.6"
-    }
-  }
-}
+	version := provider.Version
+	if version == "" {
+		version = DefaultProviderVersion
+	}
+
+	terraform := body.AppendNewBlock("terraform", nil)
+	if provider.TerraformVersion != "" {
+		terraform.Body().SetAttributeValue("required_version", cty.StringVal(provider.TerraformVersion))
+	}
+	requiredProviders := terraform.Body().AppendNewBlock("required_providers", nil)
+	requiredProviders.Body().SetAttributeValue("rollbar", cty.ObjectVal(map[string]cty.Value{
+		"source":  cty.StringVal("rollbar/rollbar"),
+		"version": cty.StringVal(version),
+	}))
+	if provider.Backend != "" {
+		backend := terraform.Body().AppendNewBlock("backend", []string{provider.Backend})
+		setBackendConfig(backend.Body(), provider.BackendConfig)
+	}
+	body.AppendNewline()
+
+	rollbar := body.AppendNewBlock("provider", []string{"rollbar"})
+	body.AppendNewline()
+
+	if provider.Variables {
+		rollbar.Body().SetAttributeTraversal("api_key", reference("var", "rollbar_api_key"))
+		rollbar.Body().SetAttributeTraversal("project_api_key", reference("var", "rollbar_project_api_key"))
+
+		appendSensitiveVariable(body, "rollbar_api_key")
+		appendSensitiveVariable(body, "rollbar_project_api_key")
+	}
 
-provider "rollbar" {
-}` + "\n\n"
-	outputFile.WriteString(resource)
-	outputFile.Sync()
-	outputFile.Close()
+	return writeHCL(file, filename)
 }
 
 // WriteProjectAccessTokens writes, to a user-defined file, the project access
-// tokens as Terraform resources.
+// tokens as Terraform resources, including their scopes and status so that a
+// plan right after the import does not try to change them.
 //
-// The name of the resource is just the name of the team, made to conform to
-// the limitations of a Terraform resource identifier via sanitizeIdentifier().
-func WriteProjectAccessTokens(projects []fetcher.Project, filename string) {
-	outputFile := writeFile(filename)
+// The name of the resource is taken from the given Names, which keeps it a
+// valid and unique Terraform resource identifier.
+func WriteProjectAccessTokens(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
 
 	for _, project := range projects {
 		for _, accessToken := range project.AccessTokens {
-			resource := `resource "rollbar_project_access_token" "` +
-				sanitizeIdentifier(project.Name) + "_" +
-				sanitizeIdentifier(accessToken.Name) + `" {` + "\n" +
-				`  name = "` + accessToken.Name + `"` + "\n" +
-				`  project_id = rollbar_project.` + sanitizeIdentifier(project.Name) + ".id" + "\n" +
-				`  depends_on = [rollbar_project.` + sanitizeIdentifier(project.Name) + `]` + "\n" +
-				`  rate_limit_window_size = ` + strconv.Itoa(accessToken.RateLimitWindowSize) + "\n" +
-				`  rate_limit_window_count = ` + strconv.Itoa(accessToken.RateLimitWindowCount) + "\n" +
-				`}` + "\n\n"
-			outputFile.WriteString(resource)
+			resource := appendResource(file.Body(), "rollbar_project_access_token",
+				names.AccessToken(project, accessToken))
+			resource.SetAttributeValue("name", cty.StringVal(accessToken.Name))
+			resource.SetAttributeTraversal("project_id", projectReference(names, project, "id"))
+			resource.SetAttributeRaw("depends_on", referenceList([]hcl.Traversal{
+				projectReference(names, project),
+			}))
+			resource.SetAttributeValue("scopes", stringList(accessToken.Scopes))
+			if accessToken.Status != "" {
+				resource.SetAttributeValue("status", cty.StringVal(accessToken.Status))
+			}
+			resource.SetAttributeValue("rate_limit_window_size", cty.NumberIntVal(int64(accessToken.RateLimitWindowSize)))
+			resource.SetAttributeValue("rate_limit_window_count", cty.NumberIntVal(int64(accessToken.RateLimitWindowCount)))
+		}
+	}
+
+	return writeHCL(file, filename)
+}
+
+// WriteProjectProviders writes, to a user-defined file, an aliased provider
+// for every project with notification rules or integrations.
+//
+// The Rollbar API manages those with a project access token, so each alias is
+// named after the project's resource and configured from a
+// rollbar_<project>_project_api_key variable, which is declared alongside it.
+func WriteProjectProviders(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+
+	for _, project := range projects {
+		if len(project.Notifications) == 0 && len(project.Integrations) == 0 {
+			continue
 		}
+		projectName := names.Project(project)
+		variable := "rollbar_" + projectName + "_project_api_key"
+
+		provider := body.AppendNewBlock("provider", []string{"rollbar"}).Body()
+		provider.SetAttributeValue("alias", cty.StringVal(projectName))
+		provider.SetAttributeTraversal("project_api_key", reference("var", variable))
+		body.AppendNewline()
+
+		appendSensitiveVariable(body, variable)
 	}
-	outputFile.Sync()
-	outputFile.Close()
+
+	return writeHCL(file, filename)
+}
+
+// WriteNotifications writes, to a user-defined file, the notification rules
+// of every project as rollbar_notification resources. Each of them uses the
+// aliased provider of its project written by WriteProjectProviders.
+//
+// As with integrations, secret settings in the config of a rule are never
+// written out. They are set from a rollbar_<resource>_<setting> variable
+// instead, named after the rule's resource and declared alongside it.
+func WriteNotifications(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+
+	for _, project := range projects {
+		projectName := names.Project(project)
+		for _, rule := range project.Notifications {
+			resource := appendResource(body, "rollbar_notification", names.Notification(project, rule))
+			resource.SetAttributeTraversal("provider", reference("rollbar", projectName))
+			resource.SetAttributeValue("channel", cty.StringVal(rule.Channel))
+
+			ruleBlock := resource.AppendNewBlock("rule", nil).Body()
+			ruleBlock.SetAttributeValue("trigger", cty.StringVal(rule.Trigger))
+			for _, filter := range rule.Filters {
+				filterBlock := ruleBlock.AppendNewBlock("filters", nil).Body()
+				filterBlock.SetAttributeValue("type", cty.StringVal(filter.Type))
+				if filter.Operation != "" {
+					filterBlock.SetAttributeValue("operation", cty.StringVal(filter.Operation))
+				}
+				if filter.Value != "" {
+					filterBlock.SetAttributeValue("value", cty.StringVal(filter.Value))
+				}
+				if filter.Period != 0 {
+					filterBlock.SetAttributeValue("period", cty.NumberIntVal(int64(filter.Period)))
+				}
+				if filter.Count != 0 {
+					filterBlock.SetAttributeValue("count", cty.NumberIntVal(int64(filter.Count)))
+				}
+			}
+
+			var variables []string
+			if len(rule.Config) > 0 {
+				configBlock := resource.AppendNewBlock("config", nil).Body()
+				variables = setSecretAttributes(configBlock, rule.Channel, rule.Config,
+					"rollbar_"+names.Notification(project, rule))
+			}
+			for _, variable := range variables {
+				appendSensitiveVariable(body, variable)
+			}
+		}
+	}
+
+	return writeHCL(file, filename)
+}
+
+// WriteIntegrations writes, to a user-defined file, the notification channel
+// integrations of every project as rollbar_integration resources, using the
+// aliased provider of their project written by WriteProjectProviders.
+//
+// Secret settings, such as PagerDuty service keys and webhook URLs, are never
+// written out. They are set from a rollbar_<project>_<channel>_<setting>
+// variable instead, which is declared alongside the resource.
+func WriteIntegrations(names *Names, projects []fetcher.Project, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+
+	for _, project := range projects {
+		projectName := names.Project(project)
+		for _, integration := range project.Integrations {
+			resource := appendResource(body, "rollbar_integration", names.Integration(project, integration))
+			resource.SetAttributeTraversal("provider", reference("rollbar", projectName))
+			channel := resource.AppendNewBlock(integration.Channel, nil).Body()
+			variables := setSecretAttributes(channel, integration.Channel, integration.Settings,
+				"rollbar_"+projectName+"_"+integration.Channel)
+
+			for _, variable := range variables {
+				appendSensitiveVariable(body, variable)
+			}
+		}
+	}
+
+	return writeHCL(file, filename)
 }
 
 // WriteProjects writes Rollbar projects as Terraform resources to the
-// user-defined file.
+// user-defined file. Projects passed to Names.UseProjectDataSource are written
+// as rollbar_project data sources instead.
 //
-// The name of the resource is just the name of the team, made to conform to
-// the limitations of a Terraform resource identifier via sanitizeIdentifier().
-func WriteProjects(projects []fetcher.Project, teams []fetcher.Team, filename string) {
-	outputFile := writeFile(filename)
+// The name of the resource is taken from the given Names, which keeps it a
+// valid and unique Terraform resource identifier.
+func WriteProjects(names *Names, projects []fetcher.Project, teams []fetcher.Team, filename string) error {
+	file := hclwrite.NewEmptyFile()
 
 	for _, project := range projects {
-		projectTeamIDs := "["
+		if names.dataProject(project) {
+			data := file.Body().AppendNewBlock("data", []string{"rollbar_project", names.Project(project)})
+			data.Body().SetAttributeValue("name", cty.StringVal(project.Name))
+			file.Body().AppendNewline()
+			continue
+		}
+
+		var projectTeams []fetcher.Team
 		for _, team := range teams {
 			for _, teamProject := range team.Projects {
 				if teamProject == project.ID {
-					projectTeamIDs += "rollbar_team." +
-						sanitizeIdentifier(team.Name) + ".id, "
+					projectTeams = append(projectTeams, team)
 				}
 			}
 		}
-		projectTeamIDs += "]"
+		projectTeams = sortedTeams(names, projectTeams)
 
-		// projectTeams = strings.ReplaceAll(projectTeams, ", ]", "]")
-
-		var resource string
-		if projectTeamIDs != "[]" {
-			resource = `resource "rollbar_project" "` + sanitizeIdentifier(project.Name) + `" {` + "\n" +
-				`  name = "` + project.Name + `"` + "\n" +
-				`  team_ids = ` + projectTeamIDs + "\n" +
-				`  depends_on = ` + strings.ReplaceAll(projectTeamIDs, ".id", "") + "\n" +
-				`}` + "\n\n"
-		} else {
-			resource = `resource "rollbar_project" "` + sanitizeIdentifier(project.Name) + `" {` + "\n" +
-				`  name = "` + project.Name + `"` + "\n" +
-				`}` + "\n\n"
+		resource := appendResource(file.Body(), "rollbar_project", names.Project(project))
+		resource.SetAttributeValue("name", cty.StringVal(project.Name))
+		if len(projectTeams) > 0 {
+			resource.SetAttributeRaw("team_ids", referenceList(teamReferences(names, projectTeams, "id")))
+			resource.SetAttributeRaw("depends_on", referenceList(teamReferences(names, projectTeams)))
 		}
-		outputFile.WriteString(resource)
 	}
-	outputFile.Sync()
-	outputFile.Close()
+
+	return writeHCL(file, filename)
 }
 
 // WriteTeams writes Rollbar teams as Terraform resources to the user-defined
 // file.
 //
-// The name of the resource is just the name of the team, made to conform to
-// the limitations of a Terraform resource identifier via sanitizeIdentifier().
-func WriteTeams(teams []fetcher.Team, filename string) {
-	outputFile := writeFile(filename)
+// The built-in Owners team cannot be managed as a regular team, so it is
+// written as a rollbar_team data source for the other resources to reference,
+// as are teams passed to Names.UseTeamDataSource.
+//
+// The name of the resource is taken from the given Names, which keeps it a
+// valid and unique Terraform resource identifier.
+func WriteTeams(names *Names, teams []fetcher.Team, filename string) error {
+	file := hclwrite.NewEmptyFile()
 
 	for _, team := range teams {
-		resource := `resource "rollbar_team" "` + sanitizeIdentifier(team.Name) + `" {` + "\n" +
-			`  name = "` + team.Name + `"` + "\n" +
-			`}` + "\n\n"
-		outputFile.WriteString(resource)
+		if names.dataTeam(team) {
+			data := file.Body().AppendNewBlock("data", []string{"rollbar_team", names.Team(team)})
+			data.Body().SetAttributeValue("team_id", cty.NumberIntVal(int64(team.ID)))
+			file.Body().AppendNewline()
+			continue
+		}
+
+		resource := appendResource(file.Body(), "rollbar_team", names.Team(team))
+		resource.SetAttributeValue("name", cty.StringVal(team.Name))
+		if team.AccessLevel != "" {
+			resource.SetAttributeValue("access_level", cty.StringVal(team.AccessLevel))
+		}
 	}
-	outputFile.Sync()
-	outputFile.Close()
+
+	return writeHCL(file, filename)
 }
 
 // WriteUsers writes all the Rollbar users as Terraform resources to the
 // user-defined file.
 //
-// The name of the resource is just the name of the team, made to conform to
-// the limitations of a Terraform resource identifier via sanitizeIdentifier().
-func WriteUsers(users []fetcher.User, filename string) {
-	outputFile := writeFile(filename)
+// The name of the resource is taken from the given Names, which keeps it a
+// valid and unique Terraform resource identifier.
+func WriteUsers(names *Names, users []fetcher.User, filename string) error {
+	file := hclwrite.NewEmptyFile()
 
 	for _, user := range users {
-		teams := "["
-		for _, team := range user.Teams {
-			teams += "rollbar_team." + sanitizeIdentifier(team.Name) + ".id, "
-		}
-		teams += "]"
+		userTeams := sortedTeams(names, user.Teams)
 
-		resource := `resource "rollbar_user" "` + sanitizeIdentifier(user.Username) + `" {` + "\n"
+		resource := appendResource(file.Body(), "rollbar_user", names.User(user))
 		if user.Email != "" {
-			resource = resource + `  email = "` + user.Email + `"` + "\n"
+			resource.SetAttributeValue("email", cty.StringVal(user.Email))
 		}
-		if len(user.Teams) > 0 {
-			resource = resource + `  team_ids = "` + teams + `"` + "\n"
+		if len(userTeams) > 0 && !names.teamUserResources {
+			resource.SetAttributeRaw("team_ids", referenceList(teamReferences(names, userTeams, "id")))
 		}
-		resource = resource + `}` + "\n\n"
-		_, err := outputFile.WriteString(resource)
-		if err != nil {
-			log.Fatal("Failed to write to file.", err)
+	}
+
+	return writeHCL(file, filename)
+}
+
+// WriteTeamUsers writes the members of every team as rollbar_team_user
+// resources to the user-defined file, one per team and email address.
+//
+// Members are the team's users, looked up in users for their email address,
+// followed by the email addresses with a pending invitation to the team, so
+// that applying the configuration does not cancel invitations that were not
+// accepted yet.
+func WriteTeamUsers(names *Names, teams []fetcher.Team, users []fetcher.User, filename string) error {
+	file := hclwrite.NewEmptyFile()
+	byID := usersByID(users)
+
+	for _, team := range teams {
+		teamID := teamReferences(names, []fetcher.Team{team}, "id")[0]
+		for _, member := range teamMembers(team, byID) {
+			resource := appendResource(file.Body(), "rollbar_team_user", names.TeamUser(team, member.email))
+			resource.SetAttributeTraversal("team_id", teamID)
+			resource.SetAttributeValue("email", cty.StringVal(member.email))
 		}
 	}
-	outputFile.Sync()
-	outputFile.Close()
+
+	return writeHCL(file, filename)
+}
+
+// WriteServiceLinks writes the service links of the account as Terraform
+// resources to the user-defined file.
+func WriteServiceLinks(names *Names, serviceLinks []fetcher.ServiceLink, filename string) error {
+	file := hclwrite.NewEmptyFile()
+
+	for _, link := range serviceLinks {
+		resource := appendResource(file.Body(), "rollbar_service_link", names.ServiceLink(link))
+		resource.SetAttributeValue("name", cty.StringVal(link.Name))
+		resource.SetAttributeValue("template", cty.StringVal(link.Template))
+	}
+
+	return writeHCL(file, filename)
 }
 
 // WriteProjectAccessTokenImportCommands extracts the project name and the
 // access token value for each access token to generate a Terraform import
 // for every access token in a given project. The resource names for the
-// projects and access tokens the same way they are for the resources
-// themselves, via sanitizeIdentifier().
-func WriteProjectAccessTokenImportCommands(projects []fetcher.Project, filename string) {
-	outputFile := writeFile(filename)
+// projects and access tokens come from the same Names as the resources
+// themselves.
+func WriteProjectAccessTokenImportCommands(names *Names, projects []fetcher.Project, filename string) error {
+	var commands strings.Builder
 	for _, project := range projects {
 		for _, accessToken := range project.AccessTokens {
-			outputFile.WriteString("terraform import rollbar_project_access_token." +
-				sanitizeIdentifier(project.Name) + "_" +
-				sanitizeIdentifier(accessToken.Name) + " " + strconv.Itoa(project.ID) +
+			commands.WriteString("terraform import rollbar_project_access_token." +
+				names.AccessToken(project, accessToken) + " " + strconv.Itoa(project.ID) +
 				"/" + accessToken.AccessToken + "\n")
 		}
 	}
-	outputFile.Sync()
-	outputFile.Close()
+	return writeFile(filename, []byte(commands.String()))
 }
 
 // WriteProjectImportCommands iterates through an array of Project structs and
 // extracts the project names and IDs from them to generate the Terraform
 // import command for each project. The resource names for the
-// projects are generated via sanitizeIdentifier() like the are done for the
-// resources themselves.
-func WriteProjectImportCommands(projects []fetcher.Project, filename string) {
-	outputFile := writeFile(filename)
+// projects come from the same Names as the resources themselves. Data source
+// projects are never imported.
+func WriteProjectImportCommands(names *Names, projects []fetcher.Project, filename string) error {
+	var commands strings.Builder
 	for _, project := range projects {
-		outputFile.WriteString("terraform import rollbar_project." +
-			sanitizeIdentifier(project.Name) + " " + strconv.Itoa(project.ID) + "\n")
+		if names.dataProject(project) {
+			continue
+		}
+		commands.WriteString("terraform import rollbar_project." +
+			names.Project(project) + " " + strconv.Itoa(project.ID) + "\n")
 	}
-	outputFile.Sync()
-	outputFile.Close()
+	return writeFile(filename, []byte(commands.String()))
 }
 
 // WriteTeamImportCommands iterates through an array of Team structs and
 // extracts the team names and IDs from them to generate the Terraform import
-// command for each project. The resource names for the teams are generated via
-// sanitizeIdentifier() like the are done for the resources themselves.
-func WriteTeamImportCommands(teams []fetcher.Team, filename string) {
-	outputFile := writeFile(filename)
+// command for each team. The resource names for the teams come from the same
+// Names as the resources themselves. The Owners team is read through a data
+// source, so it is never imported, and neither are other data source teams.
+func WriteTeamImportCommands(names *Names, teams []fetcher.Team, filename string) error {
+	var commands strings.Builder
 	for _, team := range teams {
-		outputFile.WriteString("terraform import rollbar_team." +
-			sanitizeIdentifier(team.Name) + " " + strconv.Itoa(team.ID) + "\n")
+		if names.dataTeam(team) {
+			continue
+		}
+		commands.WriteString("terraform import rollbar_team." +
+			names.Team(team) + " " + strconv.Itoa(team.ID) + "\n")
 	}
-	outputFile.Sync()
-	outputFile.Close()
+	return writeFile(filename, []byte(commands.String()))
 }
 
 // WriteUserImportCommands iterates through an array of User structs and
 // extracts the usernames and IDs from them to generate the Terraform import
-// command for each user. The resource names for the users are generated via
-// sanitizeIdentifier() like the are done for the resources themselves.
-func WriteUserImportCommands(users []fetcher.User, filename string) {
-	outputFile := writeFile(filename)
+// command for each user. The resource names for the users come from the same
+// Names as the resources themselves.
+func WriteUserImportCommands(names *Names, users []fetcher.User, filename string) error {
+	var commands strings.Builder
 	for _, user := range users {
-		outputFile.WriteString("terraform import rollbar_user." +
-			sanitizeIdentifier(user.Username) + " " + strconv.Itoa(user.ID) + "\n")
+		commands.WriteString("terraform import rollbar_user." +
+			names.User(user) + " " + strconv.Itoa(user.ID) + "\n")
 	}
-	outputFile.Sync()
-	outputFile.Close()
+	return writeFile(filename, []byte(commands.String()))
 }
 
-// writeFile accepts a filename and returns a file descriptor. This is intended
-// for writing the Terraform files and import commands to disk and to avoid
-// having to write this for every single function above.
-func writeFile(filename string) (outputFile *os.File) {
-	outputFile, err := os.OpenFile(filename,
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
+// WriteTeamUserImportCommands generates the Terraform import command for
+// every member of every team, identified by the team ID and email address
+// separated by a comma. The resource names come from the same Names as the
+// resources themselves.
+func WriteTeamUserImportCommands(names *Names, teams []fetcher.Team, users []fetcher.User, filename string) error {
+	var commands strings.Builder
+	byID := usersByID(users)
+	for _, team := range teams {
+		for _, member := range teamMembers(team, byID) {
+			commands.WriteString("terraform import rollbar_team_user." +
+				names.TeamUser(team, member.email) + " " + teamUserImportID(team, member.email) + "\n")
+		}
+	}
+	return writeFile(filename, []byte(commands.String()))
+}
+
+// WriteServiceLinkImportCommands generates the Terraform import command for
+// every service link, identified by its ID. The resource names come from the
+// same Names as the resources themselves.
+func WriteServiceLinkImportCommands(names *Names, serviceLinks []fetcher.ServiceLink, filename string) error {
+	var commands strings.Builder
+	for _, link := range serviceLinks {
+		commands.WriteString("terraform import rollbar_service_link." +
+			names.ServiceLink(link) + " " + strconv.Itoa(link.ID) + "\n")
+	}
+	return writeFile(filename, []byte(commands.String()))
+}
+
+// WriteNotificationImportCommands generates the Terraform import command for
+// every notification rule of every project, identified by its channel and
+// ID. The resource names come from the same Names as the resources
+// themselves.
+func WriteNotificationImportCommands(names *Names, projects []fetcher.Project, filename string) error {
+	var commands strings.Builder
+	for _, project := range projects {
+		for _, rule := range project.Notifications {
+			commands.WriteString("terraform import rollbar_notification." +
+				names.Notification(project, rule) + " " + notificationImportID(rule) + "\n")
+		}
+	}
+	return writeFile(filename, []byte(commands.String()))
+}
+
+// WriteIntegrationImportCommands generates the Terraform import command for
+// every integration of every project, identified by its channel. The resource
+// names come from the same Names as the resources themselves.
+func WriteIntegrationImportCommands(names *Names, projects []fetcher.Project, filename string) error {
+	var commands strings.Builder
+	for _, project := range projects {
+		for _, integration := range project.Integrations {
+			commands.WriteString("terraform import rollbar_integration." +
+				names.Integration(project, integration) + " " + integration.Channel + "\n")
+		}
 	}
+	return writeFile(filename, []byte(commands.String()))
+}
+
+// teamUserImportID is the ID a rollbar_team_user is imported by.
+func teamUserImportID(team fetcher.Team, email string) string {
+	return strconv.Itoa(team.ID) + "," + email
+}
+
+// teamMember is an email address that belongs to a team, either as one of
+// its users or through a pending invitation, along with the label its
+// rollbar_team_user is named after.
+type teamMember struct {
+	email string
+	label string
+}
 
-	return outputFile
+// teamMembers returns the members of team: its users found in byID, and then
+// its pending invitations to email addresses that are not members yet. Users
+// without an email address cannot be expressed as a rollbar_team_user and are
+// left out.
+func teamMembers(team fetcher.Team, byID map[int]fetcher.User) []teamMember {
+	var members []teamMember
+	seen := map[string]bool{}
+	for _, id := range team.Users {
+		user, ok := byID[id]
+		if !ok || user.Email == "" || seen[user.Email] {
+			continue
+		}
+		seen[user.Email] = true
+		members = append(members, teamMember{email: user.Email, label: userLabel(user)})
+	}
+	for _, invitation := range team.Invites {
+		if invitation.ToEmail == "" || seen[invitation.ToEmail] {
+			continue
+		}
+		seen[invitation.ToEmail] = true
+		members = append(members, teamMember{email: invitation.ToEmail, label: invitation.ToEmail})
+	}
+	return members
 }
 
-// sanitizeIdentifier takes a given string and tries to make it conform to the
-// limitations of valid Terraform identifiers.
-func sanitizeIdentifier(token string) (identfier string) {
-	// First, replace all the spaces and dots with underscores.
-	forbiddenUnderscore := regexp.MustCompile("[\\./, ]")
-	token = forbiddenUnderscore.ReplaceAllString(token, "_")
+func usersByID(users []fetcher.User) map[int]fetcher.User {
+	byID := make(map[int]fetcher.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID
+}
 
-	// Then, remove known invalid characters.
-	forbiddenStrip := regexp.MustCompile(`[\\(\\)\\?]`)
-	token = forbiddenStrip.ReplaceAllString(token, "")
+// notificationImportID is the ID a rollbar_notification is imported by.
+func notificationImportID(rule fetcher.NotificationRule) string {
+	return rule.Channel + ":" + strconv.Itoa(rule.ID)
+}
 
-	return token
+// sortedTeams returns the given teams sorted by resource name and without
+// duplicates, so that membership lists render the same way no matter the order
+// the API returned them in.
+func sortedTeams(names *Names, teams []fetcher.Team) []fetcher.Team {
+	seen := map[string]bool{}
+	var unique []fetcher.Team
+	for _, team := range teams {
+		name := names.Team(team)
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, team)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		return names.Team(unique[i]) < names.Team(unique[j])
+	})
+	return unique
+}
+
+// teamReferences builds a reference to each of the given teams, pointing at
+// the data source for the Owners team and other data source teams, and at the
+// resource for all others.
+func teamReferences(names *Names, teams []fetcher.Team, attrs ...string) []hcl.Traversal {
+	traversals := make([]hcl.Traversal, len(teams))
+	for i, team := range teams {
+		path := append([]string{names.Team(team)}, attrs...)
+		if names.dataTeam(team) {
+			traversals[i] = reference("data", append([]string{"rollbar_team"}, path...)...)
+		} else {
+			traversals[i] = reference("rollbar_team", path...)
+		}
+	}
+	return traversals
+}
+
+// projectReference builds a reference to project, pointing at its data source
+// or at its resource.
+func projectReference(names *Names, project fetcher.Project, attrs ...string) hcl.Traversal {
+	path := append([]string{names.Project(project)}, attrs...)
+	if names.dataProject(project) {
+		return reference("data", append([]string{"rollbar_project"}, path...)...)
+	}
+	return reference("rollbar_project", path...)
+}
+
+// appendSensitiveVariable declares a sensitive string variable, followed by a
+// blank line.
+func appendSensitiveVariable(body *hclwrite.Body, name string) {
+	variable := body.AppendNewBlock("variable", []string{name}).Body()
+	variable.SetAttributeTraversal("type", reference("string"))
+	variable.SetAttributeValue("sensitive", cty.True)
+	body.AppendNewline()
+}
+
+// setBackendConfig sets the attributes of a backend block from config,
+// sorted by key. Keys containing a dot set attributes of the nested block
+// named after their first part instead, which are written after the
+// attributes.
+func setBackendConfig(body *hclwrite.Body, config map[string]string) {
+	nested := map[string]map[string]string{}
+	var keys, blocks []string
+	for key, value := range config {
+		if i := strings.Index(key, "."); i >= 0 {
+			block := key[:i]
+			if nested[block] == nil {
+				nested[block] = map[string]string{}
+				blocks = append(blocks, block)
+			}
+			nested[block][key[i+1:]] = value
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sort.Strings(blocks)
+
+	for _, key := range keys {
+		body.SetAttributeValue(key, cty.StringVal(config[key]))
+	}
+	for _, block := range blocks {
+		setBackendConfig(body.AppendNewBlock(block, nil).Body(), nested[block])
+	}
+}
+
+// setSecretAttributes sets settings as attributes of block like
+// setAttributes, except that the secret settings of channel are set from a
+// variable named prefix_<setting> instead. It returns the names of those
+// variables, in the order of their settings.
+func setSecretAttributes(block *hclwrite.Body, channel string, settings map[string]interface{}, prefix string) []string {
+	public := map[string]interface{}{}
+	var secrets []string
+	for key, value := range settings {
+		if isSecretSetting(channel, key) {
+			secrets = append(secrets, key)
+		} else {
+			public[key] = value
+		}
+	}
+	sort.Strings(secrets)
+
+	setAttributes(block, public)
+	var variables []string
+	for _, key := range secrets {
+		variable := prefix + "_" + key
+		block.SetAttributeTraversal(key, reference("var", variable))
+		variables = append(variables, variable)
+	}
+	return variables
+}
+
+// isSecretSetting reports whether an integration setting holds a secret that
+// must not end up in the configuration: service keys, tokens, passwords and
+// webhook URLs, which commonly embed a token.
+func isSecretSetting(channel string, key string) bool {
+	for _, secret := range []string{"key", "secret", "token", "password"} {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return channel == "webhook" && key == "url"
+}
+
+// isOwnersTeam reports whether team is the built-in Owners team every account
+// has, which cannot be created, renamed or deleted like a regular team.
+func isOwnersTeam(team fetcher.Team) bool {
+	return team.AccessLevel == "owner"
+}
+
+// writeFile appends data to the given file, creating it if needed. This is
+// intended for writing the Terraform files and import commands to disk and to
+// avoid having to write this for every single function above.
+//
+// Files are opened for appending so that several writers can render into the
+// same file. They are meant to be fresh files in an Output's staging
+// directory, never the files of an earlier run.
+func writeFile(filename string, data []byte) error {
+	outputFile, err := os.OpenFile(filename,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := outputFile.Write(data); err != nil {
+		outputFile.Close()
+		return err
+	}
+	if err := outputFile.Sync(); err != nil {
+		outputFile.Close()
+		return err
+	}
+	return outputFile.Close()
 }
@@ -0,0 +1,423 @@
+package writer
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/rollbar/rollbar-terraform-importer/fetcher"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+var testTeams = []fetcher.Team{
+	{ID: 1, Name: "Owners", AccessLevel: "owner", Projects: []int{10}},
+	{ID: 2, Name: "Frontend", AccessLevel: "standard", Projects: []int{10, 11}},
+	{ID: 3, Name: "Backend", AccessLevel: "light", Projects: []int{11, 11}},
+	{ID: 4, Name: "Auditors", AccessLevel: "view"},
+}
+
+func TestWriteUsers(t *testing.T) {
+	tests := []struct {
+		golden string
+		users  []fetcher.User
+	}{
+		{
+			golden: "users_zero_teams.tf",
+			users: []fetcher.User{
+				{ID: 100, Username: "alice", Email: "alice@example.com"},
+			},
+		},
+		{
+			golden: "users_one_team.tf",
+			users: []fetcher.User{
+				{ID: 101, Username: "bob", Email: "bob@example.com", Teams: testTeams[1:2]},
+			},
+		},
+		{
+			golden: "users_many_teams.tf",
+			users: []fetcher.User{
+				{ID: 102, Username: "carol", Email: "carol@example.com", Teams: []fetcher.Team{
+					testTeams[2], testTeams[0], testTeams[1], testTeams[2],
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			names := NewNames(nil, testTeams, tt.users, nil, Overrides{})
+			filename := filepath.Join(t.TempDir(), tt.golden)
+			if err := WriteUsers(names, tt.users, filename); err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, filename, tt.golden)
+		})
+	}
+}
+
+func TestWriteTeams(t *testing.T) {
+	names := NewNames(nil, testTeams, nil, nil, Overrides{})
+	filename := filepath.Join(t.TempDir(), "teams.tf")
+	if err := WriteTeams(names, testTeams, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "teams.tf")
+}
+
+func TestWriteProjects(t *testing.T) {
+	projects := []fetcher.Project{
+		{ID: 10, Name: "web"},
+		{ID: 11, Name: "api"},
+		{ID: 12, Name: "unowned"},
+	}
+
+	names := NewNames(projects, testTeams, nil, nil, Overrides{})
+	filename := filepath.Join(t.TempDir(), "projects.tf")
+	if err := WriteProjects(names, projects, testTeams, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "projects.tf")
+}
+
+func TestWriteEscapedNames(t *testing.T) {
+	// Quotes, backslashes and template sequences must come out as literal
+	// strings rather than break the file or be interpolated.
+	projects := []fetcher.Project{
+		{ID: 20, Name: `say "hi"`},
+		{ID: 21, Name: `C:\apps\web`},
+		{ID: 22, Name: "${var.name} %{if true}x%{endif}"},
+	}
+	teams := []fetcher.Team{
+		{ID: 30, Name: `"quoted" team`, AccessLevel: "standard", Projects: []int{20}},
+		{ID: 31, Name: "${team}", AccessLevel: "light", Projects: []int{22}},
+	}
+
+	names := NewNames(projects, teams, nil, nil, Overrides{})
+	filename := filepath.Join(t.TempDir(), "escaped_names.tf")
+	if err := WriteTeams(names, teams, filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteProjects(names, projects, teams, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "escaped_names.tf")
+}
+
+func TestWriteProjectAccessTokens(t *testing.T) {
+	projects := []fetcher.Project{
+		{ID: 10, Name: "web", AccessTokens: []fetcher.AccessToken{
+			{
+				AccessToken: "aaaa", Name: "post_server_item", ProjectID: 10,
+				Scopes: []string{"post_server_item"}, Status: "enabled",
+				RateLimitWindowSize: 60, RateLimitWindowCount: 500,
+			},
+			{
+				AccessToken: "bbbb", Name: "read", ProjectID: 10,
+				Scopes: []string{"read", "write"}, Status: "disabled",
+			},
+		}},
+	}
+
+	names := NewNames(projects, nil, nil, nil, Overrides{})
+	filename := filepath.Join(t.TempDir(), "access_tokens.tf")
+	if err := WriteProjectAccessTokens(names, projects, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "access_tokens.tf")
+}
+
+func TestWriteNotifications(t *testing.T) {
+	projects := []fetcher.Project{
+		{ID: 10, Name: "web", Notifications: []fetcher.NotificationRule{
+			{
+				ID: 1, Channel: "webhook", Trigger: "reactivated_item",
+				Filters: []fetcher.NotificationFilter{{Type: "title", Operation: "within", Value: "timeout"}},
+				Config:  map[string]interface{}{"url": "https://hooks.example.com/rollbar", "format": "json"},
+			},
+			{ID: 2, Channel: "pagerduty", Trigger: "new_item"},
+			{ID: 3, Channel: "pagerduty", Trigger: "new_item"},
+		}},
+		{ID: 11, Name: "api"},
+	}
+
+	names := NewNames(projects, nil, nil, nil, Overrides{})
+	filename := filepath.Join(t.TempDir(), "notifications.tf")
+	if err := WriteProjectProviders(names, projects, filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNotifications(names, projects, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "notifications.tf")
+}
+
+func TestWriteIntegrations(t *testing.T) {
+	projects := []fetcher.Project{
+		{ID: 10, Name: "web", Integrations: []fetcher.Integration{
+			{Channel: "pagerduty", Settings: map[string]interface{}{"enabled": true, "service_key": "0f0f0f0f"}},
+			{Channel: "webhook", Settings: map[string]interface{}{"enabled": false, "url": "https://hooks.example.com/rollbar?secret=hunter2"}},
+		}},
+		{ID: 11, Name: "api"},
+	}
+
+	names := NewNames(projects, nil, nil, nil, Overrides{})
+	filename := filepath.Join(t.TempDir(), "integrations.tf")
+	if err := WriteIntegrations(names, projects, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "integrations.tf")
+}
+
+func TestWriteTeamUsers(t *testing.T) {
+	users := []fetcher.User{
+		{ID: 100, Username: "alice", Email: "alice@example.com", Teams: testTeams[0:2]},
+		{ID: 101, Username: "bob", Email: "bob@example.com", Teams: testTeams[1:2]},
+	}
+	teams := []fetcher.Team{
+		{ID: 1, Name: "Owners", AccessLevel: "owner", Users: []int{100}},
+		{ID: 2, Name: "Frontend", Users: []int{100, 101, 999}, Invites: []fetcher.Invitation{
+			{ID: 301, ToEmail: "dave@example.com", Status: "pending"},
+			{ID: 302, ToEmail: "bob@example.com", Status: "pending"},
+		}},
+	}
+
+	names := NewNames(nil, teams, users, nil, Overrides{})
+	names.UseTeamUserResources()
+	filename := filepath.Join(t.TempDir(), "team_users.tf")
+	if err := WriteTeamUsers(names, teams, users, filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteUsers(names, users, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "team_users.tf")
+}
+
+func TestWriteServiceLinks(t *testing.T) {
+	serviceLinks := []fetcher.ServiceLink{
+		{ID: 1, Name: "GitHub", Template: "https://github.com/example/web/blob/{{ code_version }}/{{ filename }}"},
+		{ID: 2, Name: "GitHub", Template: "https://github.com/example/api/blob/{{ code_version }}/{{ filename }}"},
+		{ID: 3, Name: "Grafana logs", Template: "https://grafana.example.com/explore?host=${host}"},
+	}
+
+	names := NewNames(nil, nil, nil, serviceLinks, Overrides{})
+	filename := filepath.Join(t.TempDir(), "service_links.tf")
+	if err := WriteServiceLinks(names, serviceLinks, filename); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "service_links.tf")
+}
+
+func TestWriteProviderBlocks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.tf")
+	err := WriteProviderBlocks(Provider{
+		Version:          "~> 1.4",
+		TerraformVersion: ">= 1.0",
+		Variables:        true,
+		Backend:          "s3",
+		BackendConfig: map[string]string{
+			"bucket": "terraform-state",
+			"key":    "rollbar/terraform.tfstate",
+			"region": "us-east-1",
+		},
+	}, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "main.tf")
+}
+
+func TestWriteRemoteBackend(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main_remote.tf")
+	err := WriteProviderBlocks(Provider{
+		Backend: "remote",
+		BackendConfig: map[string]string{
+			"organization":    "example",
+			"workspaces.name": "rollbar",
+		},
+	}, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, filename, "main_remote.tf")
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"web", "web"},
+		{"My App", "My_App"},
+		{"My.App", "My_App"},
+		{"my-app", "my-app"},
+		{"  spaced  out  ", "spaced_out"},
+		{"__init__", "init"},
+		{"2021 app", "project_2021_app"},
+		{"404", "project_404"},
+		{"-web", "project_web"},
+		{"Café Résumé", "Caf_R_sum"},
+		{"日本語", "project"},
+		{"---", "project"},
+		{"", "project"},
+	}
+	for _, tt := range tests {
+		if got := identifier(tt.label, "project"); got != tt.want {
+			t.Errorf("identifier(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		want    map[string]string
+	}{
+		{
+			name: "distinct labels",
+			entries: []entry{
+				{key: "1", label: "web", suffix: "1"},
+				{key: "2", label: "api", suffix: "2"},
+			},
+			want: map[string]string{"1": "web", "2": "api"},
+		},
+		{
+			name: "punctuation collision",
+			entries: []entry{
+				{key: "2", label: "My.App", suffix: "2"},
+				{key: "1", label: "My App", suffix: "1"},
+			},
+			want: map[string]string{"1": "My_App_1", "2": "My_App_2"},
+		},
+		{
+			name: "non-ASCII collision",
+			entries: []entry{
+				{key: "1", label: "日本", suffix: "1"},
+				{key: "2", label: "中文", suffix: "2"},
+			},
+			want: map[string]string{"1": "project_1", "2": "project_2"},
+		},
+		{
+			name: "leading digits",
+			entries: []entry{
+				{key: "1", label: "2021", suffix: "1"},
+				{key: "2", label: "project 2021", suffix: "2"},
+			},
+			want: map[string]string{"1": "project_2021_1", "2": "project_2021_2"},
+		},
+		{
+			name: "suffix clashes with a label",
+			entries: []entry{
+				{key: "1", label: "app", suffix: "1"},
+				{key: "2", label: "app", suffix: "2"},
+				{key: "3", label: "app_1", suffix: "3"},
+			},
+			want: map[string]string{"1": "app_1", "2": "app_2", "3": "app_1_3"},
+		},
+		{
+			name: "override named first",
+			entries: []entry{
+				{key: "1", label: "api", suffix: "1"},
+				{key: "2", label: "web", suffix: "2", override: "api"},
+			},
+			want: map[string]string{"1": "api_1", "2": "api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRegistry("project", tt.entries)
+			for key, want := range tt.want {
+				if got := r.names[key]; got != want {
+					t.Errorf("entry %s is named %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNamesAccessTokens(t *testing.T) {
+	// Token names repeat across projects, and within the web project.
+	projects := []fetcher.Project{
+		{ID: 10, Name: "web", AccessTokens: []fetcher.AccessToken{
+			{Name: "read", AccessToken: "aaaa"},
+			{Name: "write", AccessToken: "bbbb"},
+			{Name: "write", AccessToken: "cccc"},
+		}},
+		{ID: 11, Name: "api", AccessTokens: []fetcher.AccessToken{
+			{Name: "read", AccessToken: "dddd"},
+			{Name: "write", AccessToken: "eeee"},
+		}},
+	}
+	names := NewNames(projects, nil, nil, nil, Overrides{})
+
+	want := [][]string{
+		{"web_read", "web_write_" + shortHash("bbbb"), "web_write_" + shortHash("cccc")},
+		{"api_read", "api_write"},
+	}
+	for i, project := range projects {
+		for j, accessToken := range project.AccessTokens {
+			if got := names.AccessToken(project, accessToken); got != want[i][j] {
+				t.Errorf("token %q of %s is named %q, want %q", accessToken.Name, project.Name, got, want[i][j])
+			}
+		}
+	}
+}
+
+func TestNamesOverrides(t *testing.T) {
+	projects := []fetcher.Project{
+		{ID: 10, Name: "web"},
+		{ID: 11, Name: "website"},
+		{ID: 12, Name: "api"},
+	}
+	names := NewNames(projects, nil, nil, nil, Overrides{
+		Projects: map[int]string{10: "website", 12: "public api"},
+	})
+
+	want := []string{"website", "website_11", "public_api"}
+	for i, project := range projects {
+		if got := names.Project(project); got != want[i] {
+			t.Errorf("project %d is named %q, want %q", project.ID, got, want[i])
+		}
+	}
+}
+
+func TestWriteErrorsAreReturned(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "missing", "users.tf")
+	names := NewNames(nil, nil, nil, nil, Overrides{})
+	if err := WriteUsers(names, nil, filename); err == nil {
+		t.Error("expected an error writing to a missing directory")
+	}
+	if err := WriteUserImportCommands(names, nil, filename); err == nil {
+		t.Error("expected an error writing import commands to a missing directory")
+	}
+}
+
+// assertGolden compares the contents of filename with testdata/<golden>, or
+// rewrites the golden file when the tests run with -update.
+func assertGolden(t *testing.T, filename string, golden string) {
+	t.Helper()
+
+	got, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldenPath := filepath.Join("testdata", golden+".golden")
+	if *update {
+		if err := ioutil.WriteFile(goldenPath, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s does not match %s\n--- got ---\n%s\n--- want ---\n%s", filename, goldenPath, got, want)
+	}
+}